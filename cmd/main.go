@@ -9,12 +9,17 @@ import (
 	"syscall"
 
 	"github.com/cemsubasi/orderbook/internal/api"
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/cemsubasi/orderbook/internal/candle"
 	"github.com/cemsubasi/orderbook/internal/db"
 	"github.com/cemsubasi/orderbook/internal/engine"
 	"github.com/cemsubasi/orderbook/internal/event"
+	"github.com/cemsubasi/orderbook/internal/journal"
+	"github.com/cemsubasi/orderbook/internal/ticker"
 	"github.com/cemsubasi/orderbook/internal/ws"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
@@ -25,8 +30,27 @@ func main() {
 	pgHost := os.Getenv("PG_HOST")
 	kafkaHost := os.Getenv("KAFKA_HOST")
 	kafkaPort := os.Getenv("KAFKA_PORT")
+	journalDir := os.Getenv("JOURNAL_DIR")
+	transport := os.Getenv("EVENT_TRANSPORT")
+	maskL3 := os.Getenv("L3_ANONYMIZE") == "true"
 
-	if pgUser == "" || pgPass == "" {
+	apiKeys, err := auth.ParseKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		log.Println("API_KEYS:", err)
+		return
+	}
+
+	if transport == "" {
+		transport = "kafka"
+	}
+	if transport != "kafka" && transport != "memory" && transport != "postgres" {
+		log.Println("EVENT_TRANSPORT must be 'kafka', 'memory' or 'postgres'.")
+		return
+	}
+
+	// Standalone (memory) mode can run without Postgres; orders then live only
+	// in the engine and, if configured, the journal.
+	if (pgUser == "" || pgPass == "") && transport != "memory" {
 		log.Println("Environment variables not set.")
 		return
 	}
@@ -42,10 +66,13 @@ func main() {
 
 	kafkaBrokers := kafkaHost + ":" + kafkaPort
 
-	pgpool := db.InitPostgres(pgUser, pgPass, pgHost, pgDB)
-	if pgpool == nil {
-		log.Fatal("Posgres couldn't initialized.")
-		return
+	var pgpool *pgxpool.Pool
+	if pgUser != "" && pgPass != "" {
+		pgpool = db.InitPostgres(pgUser, pgPass, pgHost, pgDB)
+		if pgpool == nil {
+			log.Fatal("Posgres couldn't initialized.")
+			return
+		}
 	}
 
 	context, cancel := context.WithCancel(context.Background())
@@ -59,28 +86,116 @@ func main() {
 		cancel()
 	}()
 
-	event.StartKafkaOrderConsumer([]string{kafkaBrokers}, pgpool, context)
-	event.StartKafkaTradeConsumer([]string{kafkaBrokers}, pgpool, context)
+	var publishers map[string]engine.EventWriter
+	var consume func(topic string, groupID string, handler event.EventHandler)
+	switch transport {
+	case "kafka":
+		consume = func(topic string, groupID string, handler event.EventHandler) {
+			event.StartKafkaConsumer([]string{kafkaBrokers}, topic, groupID, handler, context)
+		}
 
-	publishers := func() map[string]engine.EventWriter {
-		return map[string]engine.EventWriter{
+		publishers = map[string]engine.EventWriter{
 			engine.OrderTopic: event.NewKafkaPublisher([]string{kafkaBrokers}, engine.OrderTopic),
 			engine.TradeTopic: event.NewKafkaPublisher([]string{kafkaBrokers}, engine.TradeTopic),
 		}
-	}()
+	case "memory":
+		bus := event.NewMemoryBus()
+		consume = func(topic string, groupID string, handler event.EventHandler) {
+			event.StartMemoryConsumer(bus, topic, handler, context)
+		}
 
-	books, err := db.RetrieveOrderBooks(pgpool, context)
-	if err != nil {
-		log.Fatal("Couldn't load existing orders from DB:", err)
-		return
+		publishers = map[string]engine.EventWriter{
+			engine.OrderTopic: event.NewMemoryPublisher(bus, engine.OrderTopic),
+			engine.TradeTopic: event.NewMemoryPublisher(bus, engine.TradeTopic),
+		}
+	case "postgres":
+		consume = func(topic string, groupID string, handler event.EventHandler) {
+			event.StartPostgresConsumer(pgpool, topic, groupID, handler, context)
+		}
+
+		publishers = map[string]engine.EventWriter{
+			engine.OrderTopic: event.NewPostgresPublisher(pgpool, engine.OrderTopic),
+			engine.TradeTopic: event.NewPostgresPublisher(pgpool, engine.TradeTopic),
+		}
+	}
+
+	if pgpool != nil {
+		consume(engine.OrderTopic, "order_handler", event.OrderPersistenceHandler(pgpool))
+		consume(engine.TradeTopic, "trade_handler", event.TradePersistenceHandler(pgpool))
+	}
+
+	candles := candle.NewAggregator(pgpool)
+	consume(engine.TradeTopic, "candle_handler", candles.Handler())
+
+	log.Printf("Using %s event transport", transport)
+
+	matchEngine := engine.NewEngine(publishers)
+
+	replayed := 0
+	if journalDir != "" {
+		var err error
+		replayed, err = journal.Replay(journalDir, matchEngine.Replay)
+		if err != nil {
+			log.Fatal("Couldn't replay journal:", err)
+			return
+		}
+		log.Printf("Replayed %d commands from journal", replayed)
 	}
 
-	engine := engine.NewEngine(publishers)
-	engine.Setup(books)
-	engine.Start(context)
+	if replayed == 0 && pgpool != nil {
+		books, err := db.RetrieveOrderBooks(pgpool, context)
+		if err != nil {
+			log.Fatal("Couldn't load existing orders from DB:", err)
+			return
+		}
+		matchEngine.Setup(books)
+	}
+
+	if journalDir != "" {
+		commandJournal, err := journal.Open(journalDir, journal.DefaultSegmentSize)
+		if err != nil {
+			log.Fatal("Couldn't open journal:", err)
+			return
+		}
+		defer commandJournal.Close()
+		matchEngine.UseJournal(commandJournal)
+
+		if replayed == 0 {
+			if err := matchEngine.JournalBooks(); err != nil {
+				log.Fatal("Couldn't journal existing orders:", err)
+				return
+			}
+		}
+	}
+
+	bookUpdates := matchEngine.BookUpdates()
+	orderUpdates := matchEngine.OrderUpdates()
+	tickerTrades := matchEngine.Trades()
+	wsTrades := matchEngine.Trades()
+	matchEngine.Start(context)
+
+	// The ticker follows live trades only, so its window starts from the
+	// trades already in Postgres. Nothing trades before the server is up.
+	tickers := ticker.NewTracker(matchEngine, matchEngine.Clock())
+	tickers.Follow(tickerTrades, context)
+	if pgpool != nil {
+		trades, err := db.ListTradesSince(pgpool, context, tickers.WindowStart())
+		if err != nil {
+			log.Fatal("Couldn't load the last day of trades:", err)
+			return
+		}
+		tickers.Apply(trades)
+	}
 
 	hub := ws.NewWsHub()
-	ws.StartWsSnapshotWorker(hub, engine, context)
+	hub.UseKeys(apiKeys)
+	hub.UseOrderEntry(matchEngine)
+	ws.StartWsSnapshotWorker(hub, matchEngine, context)
+	ws.StartWsTickerWorker(hub, tickers, context)
+	ws.StartWsBBOWorker(hub, matchEngine, context)
+	ws.StartWsBookWorker(hub, matchEngine, bookUpdates, maskL3, context)
+	ws.StartWsOrderWorker(hub, orderUpdates, context)
+	ws.StartWsTradeWorker(hub, wsTrades, context)
 
 	gin.SetMode(gin.ReleaseMode)
 
@@ -90,8 +205,14 @@ func main() {
 	gin.DefaultWriter = io.Discard
 	gin.DefaultErrorWriter = io.Discard
 
-	api.HandleOrderController(r, engine)
-	ws.HandleEventController(r, engine, hub)
+	api.HandleOrderController(r, matchEngine, pgpool, apiKeys)
+	api.HandleTradeController(r, pgpool)
+	api.HandleCandleController(r, candles, pgpool)
+	api.HandleTickerController(r, tickers)
+	api.HandleBBOController(r, matchEngine)
+	api.HandleL3Controller(r, matchEngine, maskL3)
+	api.HandleOpenAPIController(r)
+	ws.HandleEventController(r, matchEngine, hub)
 
 	if port == "" {
 		port = "8080"
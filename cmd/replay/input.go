@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/cemsubasi/orderbook/internal/journal"
+)
+
+const (
+	formatJournal = "journal"
+	formatJSONL   = "jsonl"
+	formatKafka   = "kafka"
+)
+
+func detectFormat(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return formatJournal
+	}
+
+	return formatJSONL
+}
+
+func readCommands(path string, format string, apply func(command *engine.Command) error) error {
+	switch format {
+	case formatJournal:
+		_, err := journal.Replay(path, apply)
+		return err
+	case formatJSONL:
+		return readLines(path, func(line []byte) (*engine.Command, error) {
+			return parseCommandLine(line)
+		}, apply)
+	case formatKafka:
+		return readLines(path, parseKafkaLine, apply)
+	}
+
+	return fmt.Errorf("unknown input format %q", format)
+}
+
+func readLines(path string, parse func(line []byte) (*engine.Command, error), apply func(command *engine.Command) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		command, err := parse(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if command == nil {
+			continue
+		}
+
+		if err := apply(command); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseCommandLine accepts either a journaled engine command or a bare order
+// in the shape POST /orders takes, which is treated as a new order.
+func parseCommandLine(line []byte) (*engine.Command, error) {
+	var command engine.Command
+	if err := json.Unmarshal(line, &command); err != nil {
+		return nil, err
+	}
+
+	switch command.Type {
+	case engine.NewOrderCommand, engine.CancelOrderCommand, engine.AmendOrderCommand:
+		return &command, nil
+	case "":
+		var order engine.Order
+		if err := json.Unmarshal(line, &order); err != nil {
+			return nil, err
+		}
+		return newOrderCommand(&order), nil
+	}
+
+	return nil, fmt.Errorf("unknown command type %q", command.Type)
+}
+
+// parseKafkaLine maps one message of an order_events topic dump onto the
+// command that produced it. Only resting orders are published to that topic,
+// so a dump alone cannot reproduce orders that filled on arrival.
+func parseKafkaLine(line []byte) (*engine.Command, error) {
+	var event struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, err
+	}
+
+	var order engine.Order
+	if err := json.Unmarshal(event.Payload, &order); err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case "order_added":
+		return newOrderCommand(&order), nil
+	case "order_cancelled":
+		return &engine.Command{Type: engine.CancelOrderCommand, Symbol: order.Symbol, OrderID: order.ID}, nil
+	case "order_amended":
+		return &engine.Command{Type: engine.AmendOrderCommand, Symbol: order.Symbol, OrderID: order.ID, Price: order.Price, Quantity: order.Quantity}, nil
+	}
+
+	return nil, nil
+}
+
+func newOrderCommand(order *engine.Order) *engine.Command {
+	order.Symbol = strings.ToUpper(strings.TrimSpace(order.Symbol))
+	order.Side = engine.Side(strings.ToLower(strings.TrimSpace(string(order.Side))))
+	order.Remaining = order.Quantity
+
+	return &engine.Command{Type: engine.NewOrderCommand, Order: order}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// advanceClock moves the clock to the order's own timestamp when the input
+// has one and otherwise steps it forward by a fixed amount per command.
+func advanceClock(clock *engine.ManualClock, command *engine.Command, step time.Duration) {
+	if command.Order != nil && !command.Order.CreatedAt.IsZero() {
+		clock.Set(command.Order.CreatedAt)
+		return
+	}
+	clock.Advance(step)
+}
+
+type bookState struct {
+	Symbol string              `json:"symbol"`
+	Bids   []engine.DepthLevel `json:"bids"`
+	Asks   []engine.DepthLevel `json:"asks"`
+	Orders []*engine.Order     `json:"orders"`
+}
+
+type replayResult struct {
+	Commands int             `json:"commands"`
+	Trades   []*engine.Trade `json:"trades"`
+	Books    []bookState     `json:"books"`
+}
+
+func main() {
+	input := flag.String("input", "", "journal directory, Kafka topic dump or JSONL file to replay")
+	format := flag.String("format", "", "input format: journal, kafka or jsonl (default: journal for directories, jsonl otherwise)")
+	output := flag.String("output", "", "file to write the result to (default: stdout)")
+	start := flag.String("start", "1970-01-01T00:00:00Z", "clock start time (RFC3339) for commands without a timestamp")
+	step := flag.Duration("step", time.Millisecond, "clock advance per command without a timestamp")
+	idPrefix := flag.String("id-prefix", "T", "prefix for generated trade IDs")
+	flag.Parse()
+
+	if *input == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = detectFormat(*input)
+	}
+
+	startTime, err := time.Parse(time.RFC3339, *start)
+	if err != nil {
+		log.Fatal("invalid start time:", err)
+	}
+
+	clock := engine.NewManualClock(startTime)
+	ids := engine.NewSequentialIDs(*idPrefix)
+	orderIDs := engine.NewSequentialIDs("O")
+	books := make(map[string]*engine.OrderBook)
+	result := replayResult{Trades: []*engine.Trade{}}
+
+	getBook := func(symbol string) *engine.OrderBook {
+		book, ok := books[symbol]
+		if !ok {
+			book = engine.NewOrderBook(symbol)
+			book.UseClock(clock)
+			book.UseIDGenerator(ids)
+			books[symbol] = book
+		}
+		return book
+	}
+
+	err = readCommands(*input, *format, func(command *engine.Command) error {
+		advanceClock(clock, command, *step)
+		result.Commands++
+
+		var trades []*engine.Trade
+		switch command.Type {
+		case engine.NewOrderCommand:
+			if command.Order.ID == "" {
+				command.Order.ID = orderIDs.NewID()
+			}
+			trades = getBook(command.Order.Symbol).MatchIncoming(command.Order)
+		case engine.CancelOrderCommand:
+			getBook(command.Symbol).Cancel(command.OrderID)
+		case engine.AmendOrderCommand:
+			_, trades = getBook(command.Symbol).Amend(command.OrderID, command.Price, command.Quantity)
+		}
+
+		result.Trades = append(result.Trades, trades...)
+		return nil
+	})
+	if err != nil {
+		log.Fatal("replay err:", err)
+	}
+
+	symbols := make([]string, 0, len(books))
+	for symbol := range books {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		book := books[symbol]
+		bids, asks := book.Snapshot(math.MaxInt)
+		result.Books = append(result.Books, bookState{Symbol: symbol, Bids: bids, Asks: asks, Orders: book.RestingOrders()})
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatal("create output err:", err)
+		}
+		defer out.Close()
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatal("encode result err:", err)
+	}
+}
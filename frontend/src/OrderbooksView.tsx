@@ -9,6 +9,8 @@ export default function OrderBooksView() {
   const [buyQty, setBuyQty] = useState("1");
 
   const wsRef = useRef<WebSocket | null>(null);
+  const seqRef = useRef<Record<string, number>>({});
+  const resyncingRef = useRef<Set<string>>(new Set());
   const wsRetryTimer = useRef<NodeJS.Timeout | null>(null);
 
   useEffect(() => {
@@ -41,6 +43,9 @@ export default function OrderBooksView() {
     ws.onopen = () => {
       setLoading(false);
       console.log("WebSocket connected");
+      seqRef.current = {};
+      resyncingRef.current.clear();
+      ws.send(JSON.stringify({ op: "subscribe", channels: ["depth"], symbols: ["*"] }));
       if (wsRetryTimer.current) {
         clearTimeout(wsRetryTimer.current);
         wsRetryTimer.current = null;
@@ -72,12 +77,60 @@ export default function OrderBooksView() {
   const EPS = 1e-8;
   const priceEquals = (a: number, b: number) => Math.abs(a - b) < EPS;
 
+  const applyLevel = (levels: any[], price: number, qty: number, descending: boolean) => {
+    const next = levels.filter((l) => !priceEquals(l.price, price));
+    if (qty > EPS) {
+      next.push({ price, qty });
+      next.sort((a, b) => (descending ? b.price - a.price : a.price - b.price));
+    }
+    return next;
+  };
+
+  const resync = (symbol: string) => {
+    delete seqRef.current[symbol];
+    if (resyncingRef.current.has(symbol)) return;
+    resyncingRef.current.add(symbol);
+    wsRef.current?.send(JSON.stringify({ op: "subscribe", channels: ["depth"], symbols: [symbol] }));
+  };
+
   const handleEvent = (event: any) => {
     const { type, payload } = event;
 
     if (type === "snapshot") {
       setOrderBooks(payload);
     }
+
+    if (type === "depth_snapshot") {
+      seqRef.current[payload.symbol] = payload.seq;
+      resyncingRef.current.delete(payload.symbol);
+      setOrderBooks((prev) => ({ ...prev, [payload.symbol]: { bids: payload.bids, asks: payload.asks } }));
+    }
+
+    if (type === "depth_update") {
+      const { symbol, changes } = payload;
+      if (resyncingRef.current.has(symbol)) return;
+      // A book that appears after subscribing starts empty at seq 0.
+      const known = seqRef.current[symbol] ?? (changes[0]?.seq === 1 ? 0 : undefined);
+      if (known === undefined) return resync(symbol);
+
+      let seq = known;
+      const fresh = changes.filter((c: any) => c.seq > known);
+      for (const change of fresh) {
+        if (change.seq !== seq + 1) return resync(symbol);
+        seq = change.seq;
+      }
+      seqRef.current[symbol] = seq;
+
+      setOrderBooks((prev) => {
+        let bids = prev[symbol]?.bids || [];
+        let asks = prev[symbol]?.asks || [];
+        for (const change of fresh) {
+          if (change.side === "buy") bids = applyLevel(bids, change.price, change.qty, true);
+          else asks = applyLevel(asks, change.price, change.qty, false);
+        }
+        return { ...prev, [symbol]: { bids, asks } };
+      });
+    }
   };
 
   const placeOrder = async (type: "buy" | "sell") => {
@@ -158,7 +211,7 @@ export default function OrderBooksView() {
                     </tr>
                   </thead>
                   <tbody>
-                    {(book.bids || []).map((b: any, i: number) => (
+                    {(book.bids || []).slice(0, 10).map((b: any, i: number) => (
                       <tr key={i} style={{ color: "green" }}>
                         <td style={thTdStyle}>{b.price}</td>
                         <td style={thTdStyle}>{b.qty}</td>
@@ -178,7 +231,7 @@ export default function OrderBooksView() {
                     </tr>
                   </thead>
                   <tbody>
-                    {(book.asks || []).map((a: any, i: number) => (
+                    {(book.asks || []).slice(0, 10).map((a: any, i: number) => (
                       <tr key={i} style={{ color: "red" }}>
                         <td style={thTdStyle}>{a.price}</td>
                         <td style={thTdStyle}>{a.qty}</td>
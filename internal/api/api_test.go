@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/cemsubasi/orderbook/internal/candle"
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/cemsubasi/orderbook/internal/ticker"
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter() (*gin.Engine, *engine.Engine) {
+	gin.SetMode(gin.TestMode)
+	e := engine.NewEngine(nil)
+	r := gin.New()
+	HandleOrderController(r, e, nil, auth.Keys{})
+	HandleTradeController(r, nil)
+	HandleCandleController(r, candle.NewAggregator(nil), nil)
+	HandleTickerController(r, ticker.NewTracker(e, e.Clock()))
+	HandleBBOController(r, e)
+	HandleL3Controller(r, e, false)
+	HandleOpenAPIController(r)
+
+	return r, e
+}
+
+func serve(r *gin.Engine, method string, target string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func TestValidation_ReportsParamAndCode(t *testing.T) {
+	r, _ := newTestRouter()
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		code   string
+		param  string
+	}{
+		{http.MethodGet, "/orderbook/BTC?depth=-5", "", CodeInvalidParameter, "depth"},
+		{http.MethodGet, "/orderbook/BTC?depth=abc", "", CodeInvalidParameter, "depth"},
+		{http.MethodGet, "/orderbook?depth=100000", "", CodeInvalidParameter, "depth"},
+		{http.MethodGet, "/orderbook/BTC?aggregation=0", "", CodeInvalidParameter, "aggregation"},
+		{http.MethodGet, "/orderbook/BTC%20USD", "", CodeInvalidParameter, "symbol"},
+		{http.MethodGet, "/orders?sort=up", "", CodeInvalidParameter, "sort"},
+		{http.MethodGet, "/candles/BTC?interval=2m", "", CodeInvalidParameter, "interval"},
+		{http.MethodPost, "/orders", `{"symbol":"BTC","side":"buy","price":"1","quantity":1}`, CodeInvalidBody, "price"},
+		{http.MethodPost, "/orders", `{"symbol":"BTC","side":"hold","price":1,"quantity":1}`, CodeInvalidBody, "side"},
+	}
+	for _, tc := range cases {
+		recorder := serve(r, tc.method, tc.target, tc.body)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected 400, got %d", tc.method, tc.target, recorder.Code)
+			continue
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: decode err: %v", tc.method, tc.target, err)
+		}
+		if response.Code != tc.code || response.Param != tc.param || response.Error == "" {
+			t.Errorf("%s %s: expected %s on %s, got %+v", tc.method, tc.target, tc.code, tc.param, response)
+		}
+	}
+}
+
+func TestOrderbook_UpperCasesSymbol(t *testing.T) {
+	r, e := newTestRouter()
+	e.Setup(map[string]*engine.OrderBook{"BTC": engine.NewOrderBook("BTC")})
+	e.GetBook("BTC").AddOrder(&engine.Order{ID: "o1", Symbol: "BTC", Side: engine.Buy, Price: 10, Quantity: 1, Remaining: 1})
+
+	recorder := serve(r, http.MethodGet, "/orderbook/btc", "")
+	var depth engine.BookDepth
+	if err := json.Unmarshal(recorder.Body.Bytes(), &depth); err != nil {
+		t.Fatalf("decode err: %v", err)
+	}
+	if depth.Symbol != "BTC" || len(depth.Bids) != 1 || depth.Bids[0].Qty != 1 {
+		t.Errorf("unexpected depth %+v", depth)
+	}
+}
+
+func TestOrders_PagesRestingOrdersWithoutRepeats(t *testing.T) {
+	r, e := newTestRouter()
+	e.Setup(map[string]*engine.OrderBook{"BTC": engine.NewOrderBook("BTC")})
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"o1", "o2", "o3"} {
+		// o2 and o3 share a timestamp, so the ID breaks the tie.
+		at := createdAt.Add(time.Duration(min(i, 1)) * time.Second)
+		e.GetBook("BTC").AddOrder(&engine.Order{ID: id, Symbol: "BTC", Side: engine.Buy, Price: 10, Quantity: 1, Remaining: 1, Status: engine.OrderOpen, CreatedAt: at})
+	}
+
+	for _, sort := range []string{"asc", "desc"} {
+		seen := map[string]bool{}
+		cursor := ""
+		for page := 0; page < 5; page++ {
+			recorder := serve(r, http.MethodGet, "/orders?limit=1&sort="+sort+"&cursor="+url.QueryEscape(cursor), "")
+			var response orderListResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("%s: decode err: %v", sort, err)
+			}
+			for _, order := range response.Orders {
+				if seen[order.ID] {
+					t.Fatalf("%s: order %s returned twice", sort, order.ID)
+				}
+				seen[order.ID] = true
+			}
+			if cursor = response.NextCursor; cursor == "" {
+				break
+			}
+		}
+		if len(seen) != 3 {
+			t.Errorf("%s: expected all three orders, got %v", sort, seen)
+		}
+	}
+}
+
+func TestOpenAPI_CoversEveryRoute(t *testing.T) {
+	r, _ := newTestRouter()
+
+	recorder := serve(r, http.MethodGet, "/openapi.json", "")
+	var document struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("decode err: %v", err)
+	}
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got %q", document.OpenAPI)
+	}
+
+	for _, route := range r.Routes() {
+		if route.Path == "/openapi.json" {
+			continue
+		}
+		if _, ok := document.Paths[openAPIPath(route.Path)][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate returns the account of the API key sent with the request,
+// as an "Authorization: Bearer" or X-API-Key header, or as the api_key query
+// parameter for clients such as browser WebSockets that can't set headers.
+// A request without a key is anonymous and gets an empty account; one with
+// an unknown key is rejected.
+func Authenticate(c *gin.Context, keys auth.Keys) (string, error) {
+	key := c.GetHeader("X-API-Key")
+	if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		key = bearer
+	}
+	if key == "" {
+		key = c.Query("api_key")
+	}
+	if key == "" {
+		return "", nil
+	}
+
+	account, ok := keys.Account(strings.TrimSpace(key))
+	if !ok {
+		return "", unauthorized("invalid API key")
+	}
+
+	return account, nil
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gin-gonic/gin"
+)
+
+func HandleBBOController(r *gin.Engine, e *engine.Engine) {
+	r.GET("/bbo", func(c *gin.Context) {
+		c.JSON(http.StatusOK, e.BBOs())
+	})
+
+	r.GET("/bbo/:symbol", func(c *gin.Context) {
+		symbol, err := parseSymbolParam(c)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		bbo, ok := e.BBO(symbol)
+		if !ok {
+			bbo = engine.BBO{Symbol: symbol}
+		}
+
+		c.JSON(http.StatusOK, bbo)
+	})
+}
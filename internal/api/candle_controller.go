@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/candle"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type candleListResponse struct {
+	Symbol   string          `json:"symbol"`
+	Interval candle.Interval `json:"interval"`
+	Candles  []candle.Candle `json:"candles"`
+}
+
+type candleRequest struct {
+	Symbol   string
+	Interval candle.Interval
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// parseCandleRequest fills in a missing to with the end of the open bar and
+// a missing from with limit bars before to.
+func parseCandleRequest(c *gin.Context) (candleRequest, error) {
+	var request candleRequest
+	var err error
+	if request.Symbol, err = parseSymbolParam(c); err != nil {
+		return request, err
+	}
+	if request.Interval, err = candle.ParseInterval(c.DefaultQuery("interval", string(candle.OneMinute))); err != nil {
+		return request, invalidParam("interval", err.Error())
+	}
+	if request.Limit, err = parseLimit(c.Query("limit")); err != nil {
+		return request, err
+	}
+	if request.From, request.To, err = parseTimeRange(c); err != nil {
+		return request, err
+	}
+
+	if request.To.IsZero() {
+		request.To = time.Now().UTC().Add(request.Interval.Duration())
+	}
+	if request.From.IsZero() {
+		request.From = request.To.Add(-time.Duration(request.Limit) * request.Interval.Duration())
+	}
+
+	return request, nil
+}
+
+// HandleCandleController serves OHLCV bars. Persisted bars come from
+// Postgres when it is available; the aggregator's in-memory bars, including
+// the open one, take precedence because they are always at least as recent.
+// Intervals without trades have no bar.
+func HandleCandleController(r *gin.Engine, aggregator *candle.Aggregator, pool *pgxpool.Pool) {
+	r.GET("/candles/:symbol", func(c *gin.Context) {
+		request, err := parseCandleRequest(c)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		bars := make(map[time.Time]candle.Candle)
+		if pool != nil {
+			stored, err := candle.RetrieveCandles(c.Request.Context(), pool, request.Symbol, request.Interval, request.From, request.To, request.Limit)
+			if err != nil {
+				WriteError(c, internalError("candles could not be loaded"))
+				return
+			}
+			for _, bar := range stored {
+				bars[bar.OpenTime] = *bar
+			}
+		}
+		for _, bar := range aggregator.Candles(request.Symbol, request.Interval, request.From, request.To, request.Limit) {
+			bars[bar.OpenTime] = bar
+		}
+
+		response := candleListResponse{Symbol: request.Symbol, Interval: request.Interval, Candles: make([]candle.Candle, 0, len(bars))}
+		for _, bar := range bars {
+			response.Candles = append(response.Candles, bar)
+		}
+		sort.Slice(response.Candles, func(i, j int) bool {
+			return response.Candles[i].OpenTime.Before(response.Candles[j].OpenTime)
+		})
+		if len(response.Candles) > request.Limit {
+			response.Candles = response.Candles[len(response.Candles)-request.Limit:]
+		}
+
+		c.JSON(http.StatusOK, response)
+	})
+}
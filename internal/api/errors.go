@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes returned in the "code" field of every error response.
+const (
+	CodeInvalidParameter = "invalid_parameter"
+	CodeInvalidBody      = "invalid_body"
+	CodeUnauthorized     = "unauthorized"
+	CodeNotFound         = "not_found"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Param string `json:"param,omitempty"`
+}
+
+// Error is a failure the API reports to the client. Param names the query
+// parameter, path parameter or body field at fault, if there is one.
+type Error struct {
+	Status  int
+	Code    string
+	Param   string
+	Message string
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func invalidParam(param string, message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Param: param, Message: message}
+}
+
+func invalidBody(param string, message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidBody, Param: param, Message: message}
+}
+
+func unauthorized(message string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: message}
+}
+
+func notFound(message string) *Error {
+	return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: message}
+}
+
+func unavailable(message string) *Error {
+	return &Error{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: message}
+}
+
+func internalError(message string) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: message}
+}
+
+// WriteError sends err as an error response. Anything that isn't an *Error is
+// reported as an internal error without exposing its text.
+func WriteError(c *gin.Context, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = internalError("internal error")
+	}
+
+	c.JSON(apiErr.Status, errorResponse{Error: apiErr.Message, Code: apiErr.Code, Param: apiErr.Param})
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gin-gonic/gin"
+)
+
+type l3Request struct {
+	Symbol    string
+	Depth     int
+	Anonymize bool
+}
+
+func parseL3Request(c *gin.Context) (l3Request, error) {
+	var request l3Request
+	var err error
+	if request.Symbol, err = parseSymbolParam(c); err != nil {
+		return request, err
+	}
+	if request.Depth, err = parseDepth(c.Query("depth")); err != nil {
+		return request, err
+	}
+	request.Anonymize, err = parseBool("anonymize", c.Query("anonymize"))
+
+	return request, err
+}
+
+// HandleL3Controller serves the per-order book. With maskIDs set, order IDs
+// are always masked; otherwise a client can ask for it with anonymize=true.
+func HandleL3Controller(r *gin.Engine, e *engine.Engine, maskIDs bool) {
+	r.GET("/orderbook/:symbol/l3", func(c *gin.Context) {
+		request, err := parseL3Request(c)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		book := e.L3Snapshot(request.Symbol, request.Depth)
+		if maskIDs || request.Anonymize {
+			book = book.Mask()
+		}
+
+		c.JSON(http.StatusOK, book)
+	})
+}
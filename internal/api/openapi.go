@@ -0,0 +1,345 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cemsubasi/orderbook/internal/candle"
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/cemsubasi/orderbook/internal/ticker"
+	"github.com/gin-gonic/gin"
+)
+
+type apiParam struct {
+	name        string
+	in          string
+	description string
+	schema      map[string]any
+}
+
+type apiRoute struct {
+	method   string
+	path     string
+	summary  string
+	params   []apiParam
+	body     any
+	status   int
+	response any
+	errors   []int
+}
+
+var (
+	symbolPath = apiParam{"symbol", "path", "Market symbol, case-insensitive", stringSchema()}
+
+	depthQuery       = apiParam{"depth", "query", "Number of price levels per side", integerSchema(1, MaxDepth, defaultDepth)}
+	aggregationQuery = apiParam{"aggregation", "query", "Price increment to group levels into", map[string]any{"type": "number", "exclusiveMinimum": 0}}
+	cumulativeQuery  = apiParam{"cumulative", "query", "Add a running total quantity to each level", map[string]any{"type": "boolean", "default": false}}
+	fromQuery        = apiParam{"from", "query", "Inclusive start, RFC3339 or Unix milliseconds", stringSchema()}
+	toQuery          = apiParam{"to", "query", "Exclusive end, RFC3339 or Unix milliseconds", stringSchema()}
+	sortQuery        = apiParam{"sort", "query", "Sort direction", enumSchema("desc", "asc", "desc")}
+	limitQuery       = apiParam{"limit", "query", "Page size", integerSchema(1, maxPageLimit, defaultPageLimit)}
+	cursorQuery      = apiParam{"cursor", "query", "next_cursor from the previous page", stringSchema()}
+)
+
+// apiRoutes describes every REST route for the OpenAPI document. A route
+// added to a controller must be listed here too.
+var apiRoutes = []apiRoute{
+	{
+		method: http.MethodPost, path: "/orders", summary: "Place a limit order",
+		params: []apiParam{{"X-API-Key", "header", "API key of the account placing the order; omit for an anonymous order", stringSchema()}},
+		body:   OrderCreateRequest{}, status: http.StatusAccepted, response: orderCreateResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError},
+	},
+	{
+		method: http.MethodGet, path: "/orders", summary: "List open orders and order history",
+		params: []apiParam{
+			{"symbol", "query", "Market symbol, case-insensitive", stringSchema()},
+			{"side", "query", "Order side", enumSchema("", "buy", "sell")},
+			{"status", "query", "Order status", enumSchema("", "open", "partially_filled", "filled", "cancelled", "expired")},
+			fromQuery, toQuery, sortQuery, limitQuery, cursorQuery,
+		},
+		status: http.StatusOK, response: orderListResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method: http.MethodGet, path: "/orders/:id", summary: "Get an order with its fills",
+		params: []apiParam{{"id", "path", "Order ID", stringSchema()}},
+		status: http.StatusOK, response: orderStatusResponse{},
+		errors: []int{http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method: http.MethodGet, path: "/orderbook/:symbol", summary: "Aggregated depth of one book",
+		params: []apiParam{symbolPath, depthQuery, aggregationQuery, cumulativeQuery},
+		status: http.StatusOK, response: engine.BookDepth{},
+		errors: []int{http.StatusBadRequest},
+	},
+	{
+		method: http.MethodGet, path: "/orderbook", summary: "Aggregated depth of every book, keyed by symbol",
+		params: []apiParam{depthQuery, aggregationQuery, cumulativeQuery},
+		status: http.StatusOK, response: map[string]engine.BookDepth{},
+		errors: []int{http.StatusBadRequest},
+	},
+	{
+		method: http.MethodGet, path: "/orderbook/:symbol/l3", summary: "Individual resting orders per price level",
+		params: []apiParam{symbolPath, depthQuery, {"anonymize", "query", "Replace order IDs with opaque tokens", map[string]any{"type": "boolean", "default": false}}},
+		status: http.StatusOK, response: engine.L3Book{},
+		errors: []int{http.StatusBadRequest},
+	},
+	{
+		method: http.MethodGet, path: "/bbo", summary: "Best bid and offer of every book",
+		status: http.StatusOK, response: []engine.BBO{},
+	},
+	{
+		method: http.MethodGet, path: "/bbo/:symbol", summary: "Best bid and offer of one book",
+		params: []apiParam{symbolPath},
+		status: http.StatusOK, response: engine.BBO{},
+		errors: []int{http.StatusBadRequest},
+	},
+	{
+		method: http.MethodGet, path: "/trades/:symbol", summary: "Public trade history",
+		params: []apiParam{symbolPath, fromQuery, toQuery, sortQuery, limitQuery, cursorQuery},
+		status: http.StatusOK, response: tradeListResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	},
+	{
+		method: http.MethodGet, path: "/trades/:symbol/last", summary: "Most recent trade",
+		params: []apiParam{symbolPath},
+		status: http.StatusOK, response: tradeResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	},
+	{
+		method: http.MethodGet, path: "/candles/:symbol", summary: "OHLCV bars",
+		params: []apiParam{
+			symbolPath,
+			{"interval", "query", "Bar interval", enumSchema(string(candle.OneMinute), "1m", "5m", "1h", "1d")},
+			fromQuery, toQuery, limitQuery,
+		},
+		status: http.StatusOK, response: candleListResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method: http.MethodGet, path: "/ticker", summary: "Rolling 24 hour statistics of every symbol",
+		status: http.StatusOK, response: []ticker.Ticker{},
+	},
+	{
+		method: http.MethodGet, path: "/ticker/:symbol", summary: "Rolling 24 hour statistics of one symbol",
+		params: []apiParam{symbolPath},
+		status: http.StatusOK, response: ticker.Ticker{},
+		errors: []int{http.StatusBadRequest},
+	},
+}
+
+// enumValues lists the allowed values of the named string types that appear
+// in request and response bodies.
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(engine.Side("")):        {"buy", "sell"},
+	reflect.TypeOf(engine.OrderStatus("")): {"open", "partially_filled", "filled", "cancelled", "expired"},
+	reflect.TypeOf(engine.BookAction("")):  {"open", "change", "remove"},
+	reflect.TypeOf(candle.Interval("")):    {"1m", "5m", "1h", "1d"},
+}
+
+// HandleOpenAPIController serves an OpenAPI 3 document generated from
+// apiRoutes and the Go types of the request and response bodies.
+func HandleOpenAPIController(r *gin.Engine) {
+	document, err := json.Marshal(openAPIDocument(apiRoutes))
+	if err != nil {
+		panic(err)
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", document)
+	})
+}
+
+func openAPIDocument(routes []apiRoute) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+	for _, route := range routes {
+		operation := map[string]any{
+			"summary":   route.summary,
+			"responses": openAPIResponses(route, schemas),
+		}
+
+		if len(route.params) > 0 {
+			params := make([]any, 0, len(route.params))
+			for _, param := range route.params {
+				params = append(params, map[string]any{
+					"name":        param.name,
+					"in":          param.in,
+					"description": param.description,
+					"required":    param.in == "path",
+					"schema":      param.schema,
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if route.body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaFor(reflect.TypeOf(route.body), schemas)),
+			}
+		}
+
+		path := openAPIPath(route.path)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.method)] = operation
+	}
+
+	schemaFor(reflect.TypeOf(errorResponse{}), schemas)
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Orderbook API",
+			"version": "1.0.0",
+			"description": "Errors share one body: a message in error, one of " +
+				strings.Join([]string{CodeInvalidParameter, CodeInvalidBody, CodeUnauthorized, CodeNotFound, CodeUnavailable, CodeInternal}, ", ") +
+				" in code, and the parameter or body field at fault in param when there is one.",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+func openAPIResponses(route apiRoute, schemas map[string]any) map[string]any {
+	responses := map[string]any{
+		strconv.Itoa(route.status): map[string]any{
+			"description": http.StatusText(route.status),
+			"content":     jsonContent(schemaFor(reflect.TypeOf(route.response), schemas)),
+		},
+	}
+	for _, status := range route.errors {
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content":     jsonContent(map[string]any{"$ref": "#/components/schemas/ErrorResponse"}),
+		}
+	}
+
+	return responses
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// openAPIPath turns gin's :param segments into OpenAPI's {param}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// schemaFor describes t as a JSON schema. Structs are added to schemas under
+// their type name and referenced, so shared types are described once.
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if values, ok := enumValues[t]; ok {
+		return enumSchema("", values...)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), schemas)
+	case reflect.String:
+		return stringSchema()
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, ok := schemas[name]; !ok {
+			schemas[name] = map[string]any{}
+			properties := map[string]any{}
+			var required []string
+			structProperties(t, schemas, properties, &required)
+
+			schema := map[string]any{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			schemas[name] = schema
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+
+	return map[string]any{}
+}
+
+// structProperties follows encoding/json: unexported fields and fields tagged
+// "-" are skipped, untagged embedded structs are flattened, and fields without
+// omitempty are always present.
+func structProperties(t reflect.Type, schemas map[string]any, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			structProperties(field.Type, schemas, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func schemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+func stringSchema() map[string]any {
+	return map[string]any{"type": "string"}
+}
+
+func integerSchema(minimum int, maximum int, defaultValue int) map[string]any {
+	return map[string]any{"type": "integer", "minimum": minimum, "maximum": maximum, "default": defaultValue}
+}
+
+// enumSchema lists the allowed string values, with defaultValue as the
+// default unless it is empty.
+func enumSchema(defaultValue string, values ...string) map[string]any {
+	schema := map[string]any{"type": "string", "enum": values}
+	if defaultValue != "" {
+		schema["default"] = defaultValue
+	}
+
+	return schema
+}
@@ -1,102 +1,412 @@
 package api
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/cemsubasi/orderbook/internal/db"
 	"github.com/cemsubasi/orderbook/internal/engine"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-type orderCreateRequest struct {
-	Symbol   string  `json:"symbol" binding:"required"`
-	Side     string  `json:"side" binding:"required"`
-	Price    float64 `json:"price" binding:"required"`
-	Quantity float64 `json:"quantity" binding:"required"`
+// OrderCreateRequest is the body of POST /orders, also accepted as a
+// place_order request on the WebSocket.
+type OrderCreateRequest struct {
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side"`
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
 }
 
-func HandleOrderController(r *gin.Engine, e *engine.Engine) {
-	r.POST("/orders", func(c *gin.Context) {
-		var orderRequest orderCreateRequest
+// OrderAmendRequest is the new price and total quantity of a resting order.
+type OrderAmendRequest struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
 
-		if err := c.ShouldBindBodyWithJSON(&orderRequest); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+type orderCreateResponse struct {
+	OrderID string `json:"orderId"`
+}
+
+type depthRequest struct {
+	Symbol  string
+	Options engine.DepthOptions
+}
+
+type orderFill struct {
+	TradeID    string    `json:"trade_id"`
+	Price      float64   `json:"price"`
+	Quantity   float64   `json:"quantity"`
+	Liquidity  string    `json:"liquidity,omitempty"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+type orderResponse struct {
+	ID             string             `json:"id"`
+	Symbol         string             `json:"symbol"`
+	Side           engine.Side        `json:"side"`
+	Price          float64            `json:"price"`
+	Quantity       float64            `json:"quantity"`
+	Remaining      float64            `json:"remaining"`
+	FilledQuantity float64            `json:"filled_quantity"`
+	Status         engine.OrderStatus `json:"status"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+type orderStatusResponse struct {
+	orderResponse
+	AveragePrice float64     `json:"average_price"`
+	Fills        []orderFill `json:"fills"`
+}
+
+type orderListResponse struct {
+	Orders     []orderResponse `json:"orders"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Order validates the request and builds the order it describes. The symbol
+// is upper-cased and the side lower-cased before they are checked.
+func (request OrderCreateRequest) Order() (*engine.Order, error) {
+	symbol := normalizeSymbol(request.Symbol)
+	side := engine.Side(strings.ToLower(strings.TrimSpace(request.Side)))
+
+	if symbol == "" {
+		return nil, invalidBody("symbol", "symbol is required")
+	}
+	if !validSymbol(symbol) {
+		return nil, invalidBody("symbol", symbolMessage)
+	}
+	if side != engine.Buy && side != engine.Sell {
+		return nil, invalidBody("side", "side must be 'buy' or 'sell'")
+	}
+	if err := validatePriceQuantity(request.Price, request.Quantity); err != nil {
+		return nil, err
+	}
+
+	return &engine.Order{
+		Symbol:    symbol,
+		Side:      side,
+		Price:     request.Price,
+		Quantity:  request.Quantity,
+		Remaining: request.Quantity,
+	}, nil
+}
+
+// Validate checks the new price and quantity with the same limits as new
+// orders.
+func (request OrderAmendRequest) Validate() error {
+	return validatePriceQuantity(request.Price, request.Quantity)
+}
+
+func validatePriceQuantity(price float64, quantity float64) error {
+	if price <= 0 {
+		return invalidBody("price", "price must be greater than zero")
+	}
+	if price > maxOrderValue {
+		return invalidBody("price", "price is too large")
+	}
+	if quantity <= 0 {
+		return invalidBody("quantity", "quantity must be greater than zero")
+	}
+	if quantity > maxOrderValue {
+		return invalidBody("quantity", "quantity is too large")
+	}
+
+	return nil
+}
+
+func parseDepthRequest(c *gin.Context) (depthRequest, error) {
+	var request depthRequest
+	var err error
+	if request.Symbol, err = parseSymbolParam(c); err != nil {
+		return request, err
+	}
+	request.Options, err = ParseDepthOptions(c)
+
+	return request, err
+}
+
+func newOrderResponse(order engine.Order) orderResponse {
+	return orderResponse{
+		ID:             order.ID,
+		Symbol:         order.Symbol,
+		Side:           order.Side,
+		Price:          order.Price,
+		Quantity:       order.Quantity,
+		Remaining:      order.Remaining,
+		FilledQuantity: order.Quantity - order.Remaining,
+		Status:         order.Status,
+		CreatedAt:      order.CreatedAt,
+	}
+}
+
+func newOrderStatusResponse(order engine.Order, trades []*engine.Trade) orderStatusResponse {
+	response := orderStatusResponse{
+		orderResponse: newOrderResponse(order),
+		Fills:         make([]orderFill, 0, len(trades)),
+	}
+
+	notional, filled := 0.0, 0.0
+	for _, trade := range trades {
+		fill := orderFill{
+			TradeID:    trade.ID,
+			Price:      trade.Price,
+			Quantity:   trade.Quantity,
+			ExecutedAt: trade.ExecutedAt,
+		}
+		switch order.ID {
+		case trade.MakerOrderID:
+			fill.Liquidity = "maker"
+		case trade.TakerOrderID:
+			fill.Liquidity = "taker"
+		}
+
+		response.Fills = append(response.Fills, fill)
+		notional += trade.Price * trade.Quantity
+		filled += trade.Quantity
+	}
+	if filled > 0 {
+		response.AveragePrice = notional / filled
+	}
+
+	return response
+}
+
+func parseOrderFilter(c *gin.Context) (db.OrderFilter, error) {
+	filter := db.OrderFilter{
+		Symbol: normalizeSymbol(c.Query("symbol")),
+		Side:   engine.Side(strings.ToLower(c.Query("side"))),
+		Status: engine.OrderStatus(strings.ToLower(c.Query("status"))),
+	}
+
+	if filter.Symbol != "" && !validSymbol(filter.Symbol) {
+		return filter, invalidParam("symbol", symbolMessage)
+	}
+	if filter.Side != "" && filter.Side != engine.Buy && filter.Side != engine.Sell {
+		return filter, invalidParam("side", "side must be 'buy' or 'sell'")
+	}
+
+	switch filter.Status {
+	case "", engine.OrderOpen, engine.OrderPartiallyFilled, engine.OrderFilled, engine.OrderCancelled, engine.OrderExpired:
+	default:
+		return filter, invalidParam("status", "status must be one of open, partially_filled, filled, cancelled, expired")
+	}
+
+	var err error
+	if filter.From, filter.To, err = parseTimeRange(c); err != nil {
+		return filter, err
+	}
+	if filter.Ascending, err = parseSort(c.Query("sort")); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = parseLimit(c.Query("limit")); err != nil {
+		return filter, err
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		at, id, err := decodeCursor(cursor)
+		if err != nil {
+			return filter, err
 		}
+		filter.After = &db.OrderCursor{CreatedAt: at, ID: id}
+	}
+
+	return filter, nil
+}
 
-		symbol := strings.ToUpper(strings.TrimSpace(orderRequest.Symbol))
-		side := strings.ToLower(strings.TrimSpace(orderRequest.Side))
-		price := orderRequest.Price
-		qty := orderRequest.Quantity
+func matchesOrderFilter(order engine.Order, filter db.OrderFilter) bool {
+	if filter.Symbol != "" && order.Symbol != filter.Symbol {
+		return false
+	}
+	if filter.Side != "" && order.Side != filter.Side {
+		return false
+	}
+	if filter.Status != "" && order.Status != filter.Status {
+		return false
+	}
+	if !filter.From.IsZero() && order.CreatedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !order.CreatedAt.Before(filter.To) {
+		return false
+	}
+	if filter.After != nil && !before(filter.After.CreatedAt, filter.After.ID, order.CreatedAt, order.ID, filter.Ascending) {
+		return false
+	}
+
+	return true
+}
+
+// listOrders merges a page of persisted orders with the engine's resting
+// orders. The engine is authoritative for anything still in a book, and it
+// also covers orders whose events have not reached Postgres yet.
+func listOrders(c *gin.Context, e *engine.Engine, pool *pgxpool.Pool, filter db.OrderFilter) (orderListResponse, error) {
+	orders := make(map[string]engine.Order)
+	more := false
+	var lastStored *engine.Order
+
+	if pool != nil {
+		dbFilter := filter
+		dbFilter.Limit = filter.Limit + 1
+		stored, err := db.ListOrders(pool, c.Request.Context(), dbFilter)
+		if err != nil {
+			return orderListResponse{}, err
+		}
+		for _, order := range stored {
+			orders[order.ID] = *order
+		}
+		if len(stored) > filter.Limit {
+			more = true
+			lastStored = stored[len(stored)-1]
+		}
+	}
+
+	for _, order := range e.OpenOrders() {
+		order.CreatedAt = order.CreatedAt.Truncate(time.Microsecond)
+		if _, stored := orders[order.ID]; stored || matchesOrderFilter(order, filter) {
+			orders[order.ID] = order
+		}
+	}
+
+	page := make([]engine.Order, 0, len(orders))
+	for _, order := range orders {
+		if matchesOrderFilter(order, filter) {
+			page = append(page, order)
+		}
+	}
+	sort.Slice(page, func(i, j int) bool {
+		return before(page[i].CreatedAt, page[i].ID, page[j].CreatedAt, page[j].ID, filter.Ascending)
+	})
+
+	// A stored order can drop out of the page when its live state no longer
+	// matches the status filter, so the cursor always points at the last
+	// order actually returned rather than at the end of the database page.
+	response := orderListResponse{Orders: make([]orderResponse, 0, filter.Limit)}
+	if len(page) > filter.Limit {
+		page = page[:filter.Limit]
+		more = true
+	}
+	if more {
+		if len(page) > 0 {
+			last := page[len(page)-1]
+			response.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+		} else {
+			response.NextCursor = encodeCursor(lastStored.CreatedAt, lastStored.ID)
+		}
+	}
+	for _, order := range page {
+		response.Orders = append(response.Orders, newOrderResponse(order))
+	}
 
-		if symbol == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+	return response, nil
+}
+
+// HandleOrderController registers the order routes. pool may be nil when
+// running without Postgres, in which case only resting orders can be looked up.
+// Orders placed with an API key from keys belong to that key's account.
+func HandleOrderController(r *gin.Engine, e *engine.Engine, pool *pgxpool.Pool, keys auth.Keys) {
+	r.POST("/orders", func(c *gin.Context) {
+		account, err := Authenticate(c, keys)
+		if err != nil {
+			WriteError(c, err)
 			return
 		}
 
-		if side != "buy" && side != "sell" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "side must be 'buy' or 'sell'"})
+		var request OrderCreateRequest
+		if err := bindJSON(c, &request); err != nil {
+			WriteError(c, err)
 			return
 		}
 
-		if price <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "price must be greater than zero"})
+		order, err := request.Order()
+		if err != nil {
+			WriteError(c, err)
 			return
 		}
+		order.Account = account
 
-		if qty <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be greater than zero"})
+		if err := e.Submit(order); err != nil {
+			WriteError(c, internalError("order could not be accepted"))
 			return
 		}
 
-		if price > 1e9 || qty > 1e9 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "price/quantity too large"})
+		c.JSON(http.StatusAccepted, orderCreateResponse{OrderID: order.ID})
+	})
+
+	r.GET("/orders", func(c *gin.Context) {
+		filter, err := parseOrderFilter(c)
+		if err != nil {
+			WriteError(c, err)
 			return
 		}
 
-		id := uuid.New().String()
-		order := &engine.Order{
-			ID:        id,
-			Symbol:    orderRequest.Symbol,
-			Side:      engine.Side(orderRequest.Side),
-			Price:     orderRequest.Price,
-			Quantity:  orderRequest.Quantity,
-			Remaining: orderRequest.Quantity,
+		response, err := listOrders(c, e, pool, filter)
+		if err != nil {
+			WriteError(c, internalError("orders could not be loaded"))
+			return
 		}
 
-		e.Submit(order)
-		c.JSON(http.StatusAccepted, gin.H{"orderId": id})
+		c.JSON(http.StatusOK, response)
 	})
 
-	r.GET("/orderbook/:symbol", func(c *gin.Context) {
-		symbol := c.Param("symbol")
-		depthQ := c.Query("depth")
-		depth := 10
-		if depthQ != "" {
-			fmt.Sscanf(depthQ, "%d", &depth)
+	r.GET("/orders/:id", func(c *gin.Context) {
+		id := c.Param("id")
+
+		order, live := e.FindOrder(id)
+		if !live {
+			if pool == nil {
+				WriteError(c, notFound("order not found"))
+				return
+			}
+
+			stored, err := db.RetrieveOrder(pool, c.Request.Context(), id)
+			if err != nil {
+				if errors.Is(err, db.ErrOrderNotFound) {
+					WriteError(c, notFound("order not found"))
+					return
+				}
+				WriteError(c, internalError("order could not be loaded"))
+				return
+			}
+			order = *stored
 		}
 
-		book := e.GetBook(symbol)
-		bids, asks := book.Snapshot(depth)
-		c.JSON(http.StatusOK, gin.H{"symbol": symbol, "bids": bids, "asks": asks})
+		var fills []*engine.Trade
+		if pool != nil {
+			var err error
+			fills, err = db.RetrieveOrderFills(pool, c.Request.Context(), id)
+			if err != nil {
+				WriteError(c, internalError("fills could not be loaded"))
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, newOrderStatusResponse(order, fills))
 	})
 
-	r.GET("/orderbook", func(c *gin.Context) {
-		depthQ := c.Query("depth")
-		depth := 10
-		if depthQ != "" {
-			fmt.Sscanf(depthQ, "%d", &depth)
+	r.GET("/orderbook/:symbol", func(c *gin.Context) {
+		request, err := parseDepthRequest(c)
+		if err != nil {
+			WriteError(c, err)
+			return
 		}
 
-		books := e.GetBooks()
-		result := make(map[string]gin.H)
-		for sym, book := range books {
-			bids, asks := book.Snapshot(depth)
-			result[sym] = gin.H{"bids": bids, "asks": asks}
+		depth, _ := e.Depth(request.Symbol, request.Options)
+		c.JSON(http.StatusOK, depth)
+	})
+
+	r.GET("/orderbook", func(c *gin.Context) {
+		options, err := ParseDepthOptions(c)
+		if err != nil {
+			WriteError(c, err)
+			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, e.Depths(options))
 	})
 }
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+var errInvalidCursor = invalidParam("cursor", "invalid cursor")
+
+// Cursors are opaque to clients: the sort key of the last item on a page,
+// a nanosecond timestamp and an ID, base64 encoded.
+func encodeCursor(at time.Time, id string) string {
+	raw := strconv.FormatInt(at.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return time.Time{}, "", errInvalidCursor
+	}
+
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+
+	return time.Unix(0, unixNano).UTC(), id, nil
+}
+
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, invalidParam("limit", "limit must be an integer between 1 and "+strconv.Itoa(maxPageLimit))
+	}
+
+	return limit, nil
+}
+
+func parseSort(value string) (ascending bool, err error) {
+	switch strings.ToLower(value) {
+	case "asc":
+		return true, nil
+	case "", "desc":
+		return false, nil
+	}
+
+	return false, invalidParam("sort", "sort must be 'asc' or 'desc'")
+}
+
+// parseTimeRange reads the from and to query parameters. Either may be
+// omitted, but when both are given from must come first.
+func parseTimeRange(c *gin.Context) (from time.Time, to time.Time, err error) {
+	if from, err = parseTime(c.Query("from")); err != nil {
+		return from, to, invalidParam("from", "from must be an RFC3339 timestamp or Unix milliseconds")
+	}
+	if to, err = parseTime(c.Query("to")); err != nil {
+		return from, to, invalidParam("to", "to must be an RFC3339 timestamp or Unix milliseconds")
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, invalidParam("from", "from must be before to")
+	}
+
+	return from, to, nil
+}
+
+// parseTime accepts RFC3339 timestamps or Unix milliseconds.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.UTC(), nil
+}
+
+// before reports whether key (at, id) sorts strictly before (otherAt,
+// otherID) in the requested direction. A key is never before itself, so the
+// order a cursor points at is not returned again.
+func before(at time.Time, id string, otherAt time.Time, otherID string, ascending bool) bool {
+	if !at.Equal(otherAt) {
+		return at.Before(otherAt) == ascending
+	}
+	if id == otherID {
+		return false
+	}
+
+	return (id < otherID) == ascending
+}
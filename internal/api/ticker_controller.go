@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cemsubasi/orderbook/internal/ticker"
+	"github.com/gin-gonic/gin"
+)
+
+func HandleTickerController(r *gin.Engine, tracker *ticker.Tracker) {
+	r.GET("/ticker", func(c *gin.Context) {
+		c.JSON(http.StatusOK, tracker.Tickers())
+	})
+
+	r.GET("/ticker/:symbol", func(c *gin.Context) {
+		symbol, err := parseSymbolParam(c)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, tracker.Ticker(symbol))
+	})
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/db"
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type tradeResponse struct {
+	ID         string      `json:"id"`
+	Symbol     string      `json:"symbol"`
+	Price      float64     `json:"price"`
+	Quantity   float64     `json:"quantity"`
+	TakerSide  engine.Side `json:"taker_side"`
+	ExecutedAt time.Time   `json:"executed_at"`
+}
+
+type tradeListResponse struct {
+	Trades     []tradeResponse `json:"trades"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func newTradeResponse(trade *engine.Trade) tradeResponse {
+	return tradeResponse{
+		ID:         trade.ID,
+		Symbol:     trade.Symbol,
+		Price:      trade.Price,
+		Quantity:   trade.Quantity,
+		TakerSide:  trade.TakerSide,
+		ExecutedAt: trade.ExecutedAt,
+	}
+}
+
+func parseTradeFilter(c *gin.Context) (db.TradeFilter, error) {
+	var filter db.TradeFilter
+	var err error
+	if filter.Symbol, err = parseSymbolParam(c); err != nil {
+		return filter, err
+	}
+	if filter.From, filter.To, err = parseTimeRange(c); err != nil {
+		return filter, err
+	}
+	if filter.Ascending, err = parseSort(c.Query("sort")); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = parseLimit(c.Query("limit")); err != nil {
+		return filter, err
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		at, id, err := decodeCursor(cursor)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = &db.TradeCursor{ExecutedAt: at, ID: id}
+	}
+
+	return filter, nil
+}
+
+// HandleTradeController registers the public trade history routes. They read
+// the trades table, so without Postgres they answer 503.
+func HandleTradeController(r *gin.Engine, pool *pgxpool.Pool) {
+	r.GET("/trades/:symbol", func(c *gin.Context) {
+		if pool == nil {
+			WriteError(c, unavailable("trade history is not available"))
+			return
+		}
+
+		filter, err := parseTradeFilter(c)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		query := filter
+		query.Limit = filter.Limit + 1
+		trades, err := db.ListTrades(pool, c.Request.Context(), query)
+		if err != nil {
+			WriteError(c, internalError("trades could not be loaded"))
+			return
+		}
+
+		response := tradeListResponse{Trades: make([]tradeResponse, 0, len(trades))}
+		if len(trades) > filter.Limit {
+			trades = trades[:filter.Limit]
+			last := trades[len(trades)-1]
+			response.NextCursor = encodeCursor(last.ExecutedAt, last.ID)
+		}
+		for _, trade := range trades {
+			response.Trades = append(response.Trades, newTradeResponse(trade))
+		}
+
+		c.JSON(http.StatusOK, response)
+	})
+
+	r.GET("/trades/:symbol/last", func(c *gin.Context) {
+		if pool == nil {
+			WriteError(c, unavailable("trade history is not available"))
+			return
+		}
+
+		symbol, err := parseSymbolParam(c)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		trade, err := db.RetrieveLastTrade(pool, c.Request.Context(), symbol)
+		if err != nil {
+			if errors.Is(err, db.ErrTradeNotFound) {
+				WriteError(c, notFound("no trades for symbol"))
+				return
+			}
+			WriteError(c, internalError("trade could not be loaded"))
+			return
+		}
+
+		c.JSON(http.StatusOK, newTradeResponse(trade))
+	})
+}
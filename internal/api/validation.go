@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDepth    = 10
+	MaxDepth        = 500
+	maxSymbolLength = 32
+	maxOrderValue   = 1e9
+)
+
+const symbolMessage = "symbol must be 1 to 32 letters, digits, '-', '_' or '.'"
+
+// normalizeSymbol upper-cases a symbol the same way for order entry and for
+// lookups, so "btc" and "BTC" name the same book.
+func normalizeSymbol(value string) string {
+	return strings.ToUpper(strings.TrimSpace(value))
+}
+
+func validSymbol(symbol string) bool {
+	if symbol == "" || len(symbol) > maxSymbolLength {
+		return false
+	}
+	for _, r := range symbol {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseSymbol normalizes and checks a symbol given anywhere other than the
+// request path, such as in a WebSocket subscription.
+func ParseSymbol(value string) (string, error) {
+	symbol := normalizeSymbol(value)
+	if !validSymbol(symbol) {
+		return "", invalidParam("symbol", symbolMessage)
+	}
+
+	return symbol, nil
+}
+
+func parseSymbolParam(c *gin.Context) (string, error) {
+	return ParseSymbol(c.Param("symbol"))
+}
+
+func parseDepth(value string) (int, error) {
+	if value == "" {
+		return defaultDepth, nil
+	}
+
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 1 || depth > MaxDepth {
+		return 0, invalidParam("depth", "depth must be an integer between 1 and "+strconv.Itoa(MaxDepth))
+	}
+
+	return depth, nil
+}
+
+func parseBool(param string, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, invalidParam(param, param+" must be 'true' or 'false'")
+	}
+
+	return b, nil
+}
+
+// ParseFlag reads a boolean query parameter, false when it is absent.
+func ParseFlag(c *gin.Context, param string) (bool, error) {
+	return parseBool(param, c.Query(param))
+}
+
+// ParseDepthOptions reads the depth, aggregation and cumulative query
+// parameters shared by the depth endpoints and the WebSocket snapshot feed.
+func ParseDepthOptions(c *gin.Context) (engine.DepthOptions, error) {
+	var options engine.DepthOptions
+	var err error
+	if options.Depth, err = parseDepth(c.Query("depth")); err != nil {
+		return options, err
+	}
+
+	if aggregation := c.Query("aggregation"); aggregation != "" {
+		step, err := strconv.ParseFloat(aggregation, 64)
+		if err != nil || step <= 0 || math.IsInf(step, 0) || math.IsNaN(step) {
+			return options, invalidParam("aggregation", "aggregation must be a positive price increment")
+		}
+		options.Aggregation = step
+	}
+
+	if options.Cumulative, err = parseBool("cumulative", c.Query("cumulative")); err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// bindJSON decodes the request body into request, naming the offending field
+// when a value has the wrong type.
+func bindJSON(c *gin.Context, request any) error {
+	if err := c.ShouldBindBodyWithJSON(request); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return invalidBody(typeErr.Field, typeErr.Field+" must be a "+jsonTypeName(typeErr.Type))
+		}
+		return invalidBody("", "body must be a JSON object")
+	}
+
+	return nil
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+
+	return "object"
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// Keys maps API keys to the account that orders placed with them belong to.
+// Keys are looked up by their SHA-256 digest, so the time a lookup takes
+// says nothing about how much of a guessed key was right.
+type Keys struct {
+	accounts map[[sha256.Size]byte]string
+}
+
+// ParseKeys reads a comma-separated list of key:account pairs, the format of
+// the API_KEYS environment variable. An empty value gives no keys, and then
+// every request is anonymous.
+func ParseKeys(value string) (Keys, error) {
+	keys := Keys{accounts: make(map[[sha256.Size]byte]string)}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, account, ok := strings.Cut(pair, ":")
+		key, account = strings.TrimSpace(key), strings.TrimSpace(account)
+		if !ok || key == "" || account == "" {
+			return Keys{}, fmt.Errorf("API key entries must look like key:account, got %q", pair)
+		}
+		keys.accounts[sha256.Sum256([]byte(key))] = account
+	}
+
+	return keys, nil
+}
+
+// Account returns the account key belongs to, false for an unknown key.
+func (keys Keys) Account(key string) (string, bool) {
+	account, ok := keys.accounts[sha256.Sum256([]byte(key))]
+	return account, ok
+}
@@ -0,0 +1,229 @@
+package candle
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/cemsubasi/orderbook/internal/event"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxHistory bounds how many bars per symbol and interval are kept in memory.
+// Older bars are only available from Postgres.
+const maxHistory = 1000
+
+// maxRecentTrades bounds how many trade IDs are remembered to skip
+// redelivered trades.
+const maxRecentTrades = 100000
+
+type seriesKey struct {
+	symbol   string
+	interval Interval
+}
+
+type barKey struct {
+	seriesKey
+	openTime time.Time
+}
+
+// Aggregator builds OHLCV bars from trade events. The newest bar of every
+// series is the open one and is updated as trades arrive; with a database it
+// is also upserted after each event, so the stored bar is never far behind.
+type Aggregator struct {
+	db     *pgxpool.Pool
+	series map[seriesKey][]*Candle
+	// applied holds the IDs in recent, the latest trades applied
+	applied map[string]struct{}
+	recent  []string
+	mu      sync.RWMutex
+}
+
+func NewAggregator(db *pgxpool.Pool) *Aggregator {
+	return &Aggregator{
+		db:      db,
+		series:  make(map[seriesKey][]*Candle),
+		applied: make(map[string]struct{}),
+	}
+}
+
+func (aggregator *Aggregator) Handler() event.EventHandler {
+	return func(ctx context.Context, e event.Event) {
+		if e.Type != "order_matched" {
+			return
+		}
+
+		var trades []*engine.Trade
+		if err := json.Unmarshal(e.Payload, &trades); err != nil {
+			log.Println("unmarshal trade err:", err)
+			return
+		}
+
+		aggregator.Apply(ctx, trades)
+	}
+}
+
+// Apply adds trades to their bars. Events are delivered at least once, so a
+// trade this process already applied, or one at or before the latest trade
+// of a bar loaded from Postgres, is skipped.
+func (aggregator *Aggregator) Apply(ctx context.Context, trades []*engine.Trade) {
+	stored := aggregator.loadBars(ctx, trades)
+
+	changed := aggregator.apply(trades, stored)
+	if aggregator.db == nil || len(changed) == 0 {
+		return
+	}
+	if err := upsertCandles(ctx, aggregator.db, changed); err != nil {
+		log.Println("persist candles err:", err)
+	}
+}
+
+// apply adds trades to their bars and returns copies of the bars it changed.
+func (aggregator *Aggregator) apply(trades []*engine.Trade, stored map[barKey]*Candle) []*Candle {
+	touched := make(map[*Candle]bool)
+
+	aggregator.mu.Lock()
+	defer aggregator.mu.Unlock()
+
+	for _, trade := range trades {
+		if !aggregator.remember(trade.ID) {
+			continue
+		}
+		for _, interval := range Intervals {
+			candle := aggregator.bar(trade, interval, stored)
+			if candle.restored.covers(trade.ExecutedAt, trade.ID) {
+				continue
+			}
+			candle.add(trade.Price, trade.Quantity, trade.ExecutedAt, trade.ID)
+			touched[candle] = true
+		}
+	}
+
+	changed := make([]*Candle, 0, len(touched))
+	for candle := range touched {
+		copied := *candle
+		changed = append(changed, &copied)
+	}
+
+	return changed
+}
+
+// remember records a trade ID, returning false if it was already applied.
+// Only the latest maxRecentTrades IDs are kept.
+func (aggregator *Aggregator) remember(id string) bool {
+	if id == "" {
+		return true
+	}
+	if _, ok := aggregator.applied[id]; ok {
+		return false
+	}
+
+	aggregator.applied[id] = struct{}{}
+	aggregator.recent = append(aggregator.recent, id)
+	if len(aggregator.recent) > maxRecentTrades {
+		delete(aggregator.applied, aggregator.recent[0])
+		aggregator.recent = aggregator.recent[1:]
+	}
+
+	return true
+}
+
+// loadBars reads from Postgres the bars that trades fall in but that are not
+// in memory: after a restart, or once a bar has left the in-memory history,
+// the stored bar may already hold trades. The queries run without holding
+// aggregator.mu, so they never stall readers of the other series.
+func (aggregator *Aggregator) loadBars(ctx context.Context, trades []*engine.Trade) map[barKey]*Candle {
+	if aggregator.db == nil {
+		return nil
+	}
+
+	missing := make(map[barKey]bool)
+	aggregator.mu.RLock()
+	for _, trade := range trades {
+		for _, interval := range Intervals {
+			key := barKey{seriesKey{symbol: trade.Symbol, interval: interval}, interval.Bucket(trade.ExecutedAt)}
+			if aggregator.find(key) == nil {
+				missing[key] = true
+			}
+		}
+	}
+	aggregator.mu.RUnlock()
+
+	stored := make(map[barKey]*Candle, len(missing))
+	for key := range missing {
+		candle, err := retrieveCandle(ctx, aggregator.db, key.symbol, key.interval, key.openTime)
+		if err != nil {
+			log.Println("load candle err:", err)
+		}
+		if candle != nil {
+			stored[key] = candle
+		}
+	}
+
+	return stored
+}
+
+// find returns the in-memory bar for key, nil if there is none.
+func (aggregator *Aggregator) find(key barKey) *Candle {
+	series := aggregator.series[key.seriesKey]
+	for i := len(series) - 1; i >= 0; i-- {
+		if series[i].OpenTime.Equal(key.openTime) {
+			return series[i]
+		}
+		if series[i].OpenTime.Before(key.openTime) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// bar finds or opens the bar a trade falls in. Trade events can arrive
+// slightly out of order, so a trade for an earlier bar still lands in it as
+// long as that bar is within the in-memory history. A bar that is not in
+// memory starts from its stored copy, if loadBars found one.
+func (aggregator *Aggregator) bar(trade *engine.Trade, interval Interval, stored map[barKey]*Candle) *Candle {
+	key := barKey{seriesKey{symbol: trade.Symbol, interval: interval}, interval.Bucket(trade.ExecutedAt)}
+	if candle := aggregator.find(key); candle != nil {
+		return candle
+	}
+
+	candle, ok := stored[key]
+	if !ok {
+		candle = newCandle(trade.Symbol, interval, key.openTime, trade.Price, trade.ExecutedAt)
+	}
+
+	series := append(aggregator.series[key.seriesKey], candle)
+	sort.Slice(series, func(i, j int) bool { return series[i].OpenTime.Before(series[j].OpenTime) })
+	if len(series) > maxHistory {
+		series = series[len(series)-maxHistory:]
+	}
+	aggregator.series[key.seriesKey] = series
+
+	return candle
+}
+
+// Candles returns copies of the in-memory bars with open times in [from, to),
+// oldest first, keeping the newest limit bars.
+func (aggregator *Aggregator) Candles(symbol string, interval Interval, from time.Time, to time.Time, limit int) []Candle {
+	aggregator.mu.RLock()
+	defer aggregator.mu.RUnlock()
+
+	var candles []Candle
+	for _, candle := range aggregator.series[seriesKey{symbol: symbol, interval: interval}] {
+		if candle.OpenTime.Before(from) || !candle.OpenTime.Before(to) {
+			continue
+		}
+		candles = append(candles, *candle)
+	}
+
+	if len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+
+	return candles
+}
@@ -0,0 +1,85 @@
+package candle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+func TestAggregator_BuildsBarsPerInterval(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	aggregator := NewAggregator(nil)
+
+	aggregator.Apply(context.Background(), []*engine.Trade{
+		{Symbol: "SYM", Price: 100, Quantity: 1, ExecutedAt: base.Add(10 * time.Second)},
+		{Symbol: "SYM", Price: 105, Quantity: 2, ExecutedAt: base.Add(20 * time.Second)},
+		{Symbol: "SYM", Price: 95, Quantity: 1, ExecutedAt: base.Add(70 * time.Second)},
+	})
+	// arrives late but belongs before the 105 print
+	aggregator.Apply(context.Background(), []*engine.Trade{
+		{Symbol: "SYM", Price: 99, Quantity: 1, ExecutedAt: base.Add(5 * time.Second)},
+	})
+
+	minutes := aggregator.Candles("SYM", OneMinute, base, base.Add(time.Hour), 10)
+	if len(minutes) != 2 {
+		t.Fatalf("expected 2 one-minute bars, got %d", len(minutes))
+	}
+
+	first := minutes[0]
+	if first.Open != 99 || first.High != 105 || first.Low != 99 || first.Close != 105 {
+		t.Errorf("unexpected first bar OHLC %v/%v/%v/%v", first.Open, first.High, first.Low, first.Close)
+	}
+	if first.Volume != 4 || first.QuoteVolume != 409 || first.Trades != 3 {
+		t.Errorf("unexpected first bar volume %v quote %v trades %d", first.Volume, first.QuoteVolume, first.Trades)
+	}
+
+	hours := aggregator.Candles("SYM", OneHour, base, base.Add(time.Hour), 10)
+	if len(hours) != 1 || hours[0].Close != 95 || hours[0].Volume != 5 {
+		t.Fatalf("expected one hourly bar closing at 95 with volume 5, got %+v", hours)
+	}
+}
+
+func TestAggregator_ContinuesStoredBar(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	aggregator := NewAggregator(nil)
+
+	// the stored minute bar already counts t1, applied before a restart
+	minuteKey := barKey{seriesKey{symbol: "SYM", interval: OneMinute}, base}
+	storedMinute := newCandle("SYM", OneMinute, base, 100, base)
+	storedMinute.add(100, 1, base.Add(5*time.Second), "t1")
+	storedMinute.restored = storedMinute.latest
+	stored := map[barKey]*Candle{minuteKey: storedMinute}
+
+	aggregator.apply([]*engine.Trade{
+		{ID: "t1", Symbol: "SYM", Price: 100, Quantity: 1, ExecutedAt: base.Add(5 * time.Second)},
+		{ID: "t2", Symbol: "SYM", Price: 90, Quantity: 2, ExecutedAt: base.Add(10 * time.Second)},
+	}, stored)
+
+	minute := aggregator.Candles("SYM", OneMinute, base, base.Add(time.Hour), 10)
+	if len(minute) != 1 || minute[0].Open != 100 || minute[0].Low != 90 || minute[0].Volume != 3 || minute[0].Trades != 2 {
+		t.Fatalf("expected the stored minute bar to continue without t1 again, got %+v", minute)
+	}
+	five := aggregator.Candles("SYM", FiveMinutes, base, base.Add(time.Hour), 10)
+	if len(five) != 1 || five[0].Open != 100 || five[0].Volume != 3 {
+		t.Fatalf("expected a fresh five-minute bar with both trades, got %+v", five)
+	}
+}
+
+func TestAggregator_SkipsRedeliveredTrades(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	aggregator := NewAggregator(nil)
+
+	first := &engine.Trade{ID: "t1", Symbol: "SYM", Price: 100, Quantity: 1, ExecutedAt: base.Add(10 * time.Second)}
+	aggregator.Apply(context.Background(), []*engine.Trade{first})
+	aggregator.Apply(context.Background(), []*engine.Trade{
+		first,
+		{ID: "t2", Symbol: "SYM", Price: 101, Quantity: 1, ExecutedAt: base.Add(20 * time.Second)},
+	})
+
+	minute := aggregator.Candles("SYM", OneMinute, base, base.Add(time.Hour), 10)
+	if len(minute) != 1 || minute[0].Volume != 2 || minute[0].Trades != 2 {
+		t.Fatalf("expected t1 counted once, got %+v", minute)
+	}
+}
@@ -0,0 +1,126 @@
+package candle
+
+import (
+	"fmt"
+	"time"
+)
+
+type Interval string
+
+const (
+	OneMinute   Interval = "1m"
+	FiveMinutes Interval = "5m"
+	OneHour     Interval = "1h"
+	OneDay      Interval = "1d"
+)
+
+var Intervals = []Interval{OneMinute, FiveMinutes, OneHour, OneDay}
+
+func (interval Interval) Duration() time.Duration {
+	switch interval {
+	case OneMinute:
+		return time.Minute
+	case FiveMinutes:
+		return 5 * time.Minute
+	case OneHour:
+		return time.Hour
+	case OneDay:
+		return 24 * time.Hour
+	}
+
+	return 0
+}
+
+func ParseInterval(value string) (Interval, error) {
+	interval := Interval(value)
+	if interval.Duration() == 0 {
+		return "", fmt.Errorf("interval must be one of 1m, 5m, 1h, 1d")
+	}
+
+	return interval, nil
+}
+
+// Bucket returns the open time of the bar containing at. Bars are aligned to
+// UTC, so daily bars run from midnight to midnight UTC.
+func (interval Interval) Bucket(at time.Time) time.Time {
+	return at.UTC().Truncate(interval.Duration())
+}
+
+type Candle struct {
+	Symbol      string    `json:"symbol"`
+	Interval    Interval  `json:"interval"`
+	OpenTime    time.Time `json:"open_time"`
+	CloseTime   time.Time `json:"close_time"`
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	Close       float64   `json:"close"`
+	Volume      float64   `json:"volume"`
+	QuoteVolume float64   `json:"quote_volume"`
+	Trades      int64     `json:"trades"`
+
+	// first and last trade times seen by this process, used to keep open and
+	// close right when trade events arrive out of order
+	firstAt time.Time
+	lastAt  time.Time
+	// latest is the latest trade counted in the bar and is stored with it.
+	// restored is the latest one when the bar was loaded from storage: trades
+	// at or before it were counted before a restart.
+	latest   tradeMark
+	restored tradeMark
+}
+
+// tradeMark orders trades by execution time, then ID. Times are kept to the
+// microsecond, as Postgres stores them.
+type tradeMark struct {
+	at time.Time
+	id string
+}
+
+// covers reports whether a trade executed at at with ID id is at or before
+// the mark. The zero mark covers nothing.
+func (mark tradeMark) covers(at time.Time, id string) bool {
+	if mark.at.IsZero() {
+		return false
+	}
+
+	at = at.UTC().Truncate(time.Microsecond)
+	return at.Before(mark.at) || at.Equal(mark.at) && id <= mark.id
+}
+
+func newCandle(symbol string, interval Interval, openTime time.Time, price float64, at time.Time) *Candle {
+	return &Candle{
+		Symbol:    symbol,
+		Interval:  interval,
+		OpenTime:  openTime,
+		CloseTime: openTime.Add(interval.Duration()),
+		Open:      price,
+		High:      price,
+		Low:       price,
+		Close:     price,
+		firstAt:   at,
+	}
+}
+
+func (candle *Candle) add(price float64, quantity float64, at time.Time, id string) {
+	if !candle.latest.covers(at, id) {
+		candle.latest = tradeMark{at: at.UTC().Truncate(time.Microsecond), id: id}
+	}
+	if price > candle.High {
+		candle.High = price
+	}
+	if price < candle.Low {
+		candle.Low = price
+	}
+	if !candle.firstAt.IsZero() && at.Before(candle.firstAt) {
+		candle.Open = price
+		candle.firstAt = at
+	}
+	if !at.Before(candle.lastAt) {
+		candle.Close = price
+		candle.lastAt = at
+	}
+	candle.Volume += quantity
+	candle.QuoteVolume += price * quantity
+	candle.Trades++
+}
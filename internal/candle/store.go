@@ -0,0 +1,94 @@
+package candle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func upsertCandles(ctx context.Context, db *pgxpool.Pool, candles []*Candle) error {
+	batch := &pgx.Batch{}
+	for _, c := range candles {
+		batch.Queue(`INSERT INTO candles (symbol, period, open_time, open, high, low, close, volume, quote_volume, trades, last_trade_at, last_trade_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (symbol, period, open_time) DO UPDATE SET
+				high = EXCLUDED.high, low = EXCLUDED.low, close = EXCLUDED.close,
+				volume = EXCLUDED.volume, quote_volume = EXCLUDED.quote_volume, trades = EXCLUDED.trades,
+				last_trade_at = EXCLUDED.last_trade_at, last_trade_id = EXCLUDED.last_trade_id`,
+			c.Symbol, c.Interval, c.OpenTime, c.Open, c.High, c.Low, c.Close, c.Volume, c.QuoteVolume, c.Trades, c.latest.at, c.latest.id)
+	}
+
+	return db.SendBatch(ctx, batch).Close()
+}
+
+func retrieveCandle(ctx context.Context, db *pgxpool.Pool, symbol string, interval Interval, openTime time.Time) (*Candle, error) {
+	row := db.QueryRow(ctx, `SELECT symbol, period, open_time, open, high, low, close, volume, quote_volume, trades, last_trade_at, last_trade_id
+		FROM candles WHERE symbol = $1 AND period = $2 AND open_time = $3`, symbol, interval, openTime)
+
+	candle, err := scanCandle(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return candle, err
+}
+
+// RetrieveCandles returns the persisted bars of a series with open times in
+// [from, to), oldest first.
+func RetrieveCandles(ctx context.Context, db *pgxpool.Pool, symbol string, interval Interval, from time.Time, to time.Time, limit int) ([]*Candle, error) {
+	rows, err := db.Query(ctx, `SELECT symbol, period, open_time, open, high, low, close, volume, quote_volume, trades, last_trade_at, last_trade_id
+		FROM candles
+		WHERE symbol = $1 AND period = $2 AND open_time >= $3 AND open_time < $4
+		ORDER BY open_time DESC
+		LIMIT $5`, symbol, interval, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query candles err: %w", err)
+	}
+	defer rows.Close()
+
+	candles := []*Candle{}
+	for rows.Next() {
+		candle, err := scanCandle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan candle err: %w", err)
+		}
+		candles = append(candles, candle)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+
+	return candles, nil
+}
+
+// scanCandle reads a stored bar. Its latest trade, if it has one, is the mark
+// that redelivered trades are checked against; bars stored before it was
+// recorded have none.
+func scanCandle(row pgx.Row) (*Candle, error) {
+	var candle Candle
+	var lastTradeAt *time.Time
+	var lastTradeID *string
+	err := row.Scan(&candle.Symbol, &candle.Interval, &candle.OpenTime, &candle.Open, &candle.High, &candle.Low,
+		&candle.Close, &candle.Volume, &candle.QuoteVolume, &candle.Trades, &lastTradeAt, &lastTradeID)
+	if err != nil {
+		return nil, err
+	}
+	if lastTradeAt != nil && lastTradeID != nil {
+		candle.latest = tradeMark{at: lastTradeAt.UTC(), id: *lastTradeID}
+		candle.restored = candle.latest
+	}
+
+	candle.OpenTime = candle.OpenTime.UTC()
+	candle.CloseTime = candle.OpenTime.Add(candle.Interval.Duration())
+
+	return &candle, nil
+}
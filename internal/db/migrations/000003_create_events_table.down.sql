@@ -0,0 +1,2 @@
+DROP TABLE event_offsets;
+DROP TABLE events;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS events (
+	id BIGSERIAL PRIMARY KEY,
+	topic TEXT NOT NULL,
+	type TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS events_topic_id_idx ON events (topic, id);
+
+CREATE TABLE IF NOT EXISTS event_offsets (
+	group_id TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	last_id BIGINT NOT NULL,
+	PRIMARY KEY (group_id, topic)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS trades_sell_order_id_idx;
+DROP INDEX IF EXISTS trades_buy_order_id_idx;
+DROP INDEX IF EXISTS orders_symbol_created_at_idx;
+DROP INDEX IF EXISTS orders_created_at_id_idx;
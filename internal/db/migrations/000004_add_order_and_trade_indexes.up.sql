@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS orders_created_at_id_idx ON orders (created_at, id);
+CREATE INDEX IF NOT EXISTS orders_symbol_created_at_idx ON orders (symbol, created_at);
+CREATE INDEX IF NOT EXISTS trades_buy_order_id_idx ON trades (buy_order_id);
+CREATE INDEX IF NOT EXISTS trades_sell_order_id_idx ON trades (sell_order_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS trades_symbol_executed_at_id_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS trades_symbol_executed_at_id_idx ON trades (symbol, executed_at, id);
@@ -0,0 +1,3 @@
+ALTER TABLE trades DROP COLUMN IF EXISTS taker_side;
+ALTER TABLE trades DROP COLUMN IF EXISTS taker_order_id;
+ALTER TABLE trades DROP COLUMN IF EXISTS maker_order_id;
@@ -0,0 +1,3 @@
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS maker_order_id TEXT;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS taker_order_id TEXT;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS taker_side TEXT;
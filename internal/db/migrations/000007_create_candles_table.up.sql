@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS candles (
+	symbol TEXT NOT NULL,
+	period TEXT NOT NULL,
+	open_time TIMESTAMP NOT NULL,
+	open NUMERIC NOT NULL,
+	high NUMERIC NOT NULL,
+	low NUMERIC NOT NULL,
+	close NUMERIC NOT NULL,
+	volume NUMERIC NOT NULL,
+	quote_volume NUMERIC NOT NULL,
+	trades BIGINT NOT NULL,
+	PRIMARY KEY (symbol, period, open_time)
+);
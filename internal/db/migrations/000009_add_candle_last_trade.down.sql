@@ -0,0 +1,2 @@
+ALTER TABLE candles DROP COLUMN IF EXISTS last_trade_at;
+ALTER TABLE candles DROP COLUMN IF EXISTS last_trade_id;
@@ -0,0 +1,2 @@
+ALTER TABLE candles ADD COLUMN IF NOT EXISTS last_trade_at TIMESTAMP;
+ALTER TABLE candles ADD COLUMN IF NOT EXISTS last_trade_id TEXT;
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+// Resting orders are not rewritten as they fill, so remaining quantity and
+// fill status are derived from the trades table. Cancelled and expired are
+// the only statuses that have to come from the row itself.
+const orderStateQuery = `
+SELECT id, symbol, side, price, quantity, remaining, status, created_at
+FROM (
+    SELECT
+        o.id,
+        o.symbol,
+        o.side,
+        o.price,
+        o.quantity,
+        o.quantity - COALESCE(matched.total_traded, 0) AS remaining,
+        CASE
+            WHEN o.status IN ('cancelled', 'expired') THEN o.status
+            WHEN o.quantity - COALESCE(matched.total_traded, 0) <= 0 THEN 'filled'
+            WHEN COALESCE(matched.total_traded, 0) > 0 THEN 'partially_filled'
+            ELSE 'open'
+        END AS status,
+        o.created_at
+    FROM orders o
+    LEFT JOIN LATERAL (
+        SELECT SUM(quantity) AS total_traded
+        FROM trades
+        WHERE buy_order_id = o.id OR sell_order_id = o.id
+    ) matched ON TRUE
+) state
+`
+
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// OrderFilter selects orders for ListOrders. Zero values mean "any"; From is
+// inclusive, To is exclusive and After continues past a previous page.
+type OrderFilter struct {
+	Symbol    string
+	Side      engine.Side
+	Status    engine.OrderStatus
+	From      time.Time
+	To        time.Time
+	After     *OrderCursor
+	Ascending bool
+	Limit     int
+}
+
+func RetrieveOrder(pool *pgxpool.Pool, context context.Context, orderID string) (*engine.Order, error) {
+	order, err := scanOrder(pool.QueryRow(context, orderStateQuery+`WHERE id = $1`, orderID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("query order err: %w", err)
+	}
+
+	return order, nil
+}
+
+func ListOrders(pool *pgxpool.Pool, context context.Context, filter OrderFilter) ([]*engine.Order, error) {
+	var conditions []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Symbol != "" {
+		conditions = append(conditions, "symbol = "+arg(filter.Symbol))
+	}
+	if filter.Side != "" {
+		conditions = append(conditions, "side = "+arg(filter.Side))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+arg(filter.Status))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.To))
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)", comparison, arg(filter.After.CreatedAt), arg(filter.After.ID)))
+	}
+
+	query := orderStateQuery
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+	query += fmt.Sprintf("ORDER BY created_at %s, id %s LIMIT %s", direction, direction, arg(filter.Limit))
+
+	rows, err := pool.Query(context, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query orders err: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*engine.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan order err: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	return orders, nil
+}
+
+func scanOrder(row pgx.Row) (*engine.Order, error) {
+	var order engine.Order
+	err := row.Scan(&order.ID, &order.Symbol, &order.Side, &order.Price, &order.Quantity,
+		&order.Remaining, &order.Status, &order.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func RetrieveOrderFills(pool *pgxpool.Pool, context context.Context, orderID string) ([]*engine.Trade, error) {
+	rows, err := pool.Query(context, tradeQuery+`
+WHERE t.buy_order_id = $1 OR t.sell_order_id = $1
+ORDER BY t.executed_at, t.id;
+    `, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("query fills err: %w", err)
+	}
+	defer rows.Close()
+
+	fills := []*engine.Trade{}
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan fill err: %w", err)
+		}
+		fills = append(fills, trade)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	return fills, nil
+}
@@ -29,7 +29,9 @@ func RetrieveOrderBooks(pool *pgxpool.Pool, context context.Context) (map[string
     o.side,
     o.price,
     o.quantity,
-    o.quantity - COALESCE(matched.total_traded, 0) AS remaining
+    o.quantity - COALESCE(matched.total_traded, 0) AS remaining,
+    o.created_at,
+    COALESCE(o.account, '')
 FROM orders o
 LEFT JOIN (
     SELECT 
@@ -47,6 +49,7 @@ LEFT JOIN (
     GROUP BY sell_order_id
 ) matched ON o.id = matched.order_id
 WHERE (o.quantity - COALESCE(matched.total_traded, 0)) > 0
+  AND (o.status IS NULL OR o.status NOT IN ('cancelled', 'expired'))
 ORDER BY o.symbol,
          CASE WHEN o.side='buy' THEN -o.price ELSE o.price END,
          o.created_at;
@@ -62,7 +65,7 @@ ORDER BY o.symbol,
 
 	for rows.Next() {
 		var order engine.Order
-		if err := rows.Scan(&order.ID, &order.Symbol, &order.Side, &order.Price, &order.Quantity, &order.Remaining); err != nil {
+		if err := rows.Scan(&order.ID, &order.Symbol, &order.Side, &order.Price, &order.Quantity, &order.Remaining, &order.CreatedAt, &order.Account); err != nil {
 			log.Println("scan order err:", err)
 			continue
 		}
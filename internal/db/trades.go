@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrTradeNotFound = errors.New("trade not found")
+
+// Trades persisted before taker information was recorded fall back to the
+// order that arrived last as the aggressor, since a trade only happens when an
+// incoming order meets one already resting in the book.
+const tradeQuery = `
+SELECT
+    t.id,
+    t.symbol,
+    t.buy_order_id,
+    t.sell_order_id,
+    COALESCE(t.maker_order_id, ''),
+    COALESCE(t.taker_order_id, ''),
+    COALESCE(t.taker_side, CASE
+        WHEN b.created_at IS NULL OR s.created_at IS NULL THEN ''
+        WHEN b.created_at > s.created_at THEN 'buy'
+        ELSE 'sell'
+    END) AS taker_side,
+    t.price,
+    t.quantity,
+    t.executed_at
+FROM trades t
+LEFT JOIN orders b ON t.taker_side IS NULL AND b.id = t.buy_order_id
+LEFT JOIN orders s ON t.taker_side IS NULL AND s.id = t.sell_order_id
+`
+
+type TradeCursor struct {
+	ExecutedAt time.Time
+	ID         string
+}
+
+// TradeFilter selects trades of one symbol. From is inclusive, To is
+// exclusive and After continues past a previous page.
+type TradeFilter struct {
+	Symbol    string
+	From      time.Time
+	To        time.Time
+	After     *TradeCursor
+	Ascending bool
+	Limit     int
+}
+
+func ListTrades(pool *pgxpool.Pool, context context.Context, filter TradeFilter) ([]*engine.Trade, error) {
+	args := []any{filter.Symbol}
+	conditions := []string{"t.symbol = $1"}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "t.executed_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "t.executed_at < "+arg(filter.To))
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(t.executed_at, t.id) %s (%s, %s)", comparison, arg(filter.After.ExecutedAt), arg(filter.After.ID)))
+	}
+
+	query := tradeQuery + "WHERE " + strings.Join(conditions, " AND ") + "\n" +
+		fmt.Sprintf("ORDER BY t.executed_at %s, t.id %s LIMIT %s", direction, direction, arg(filter.Limit))
+
+	rows, err := pool.Query(context, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query trades err: %w", err)
+	}
+	defer rows.Close()
+
+	trades := []*engine.Trade{}
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan trade err: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	return trades, nil
+}
+
+// ListTradesSince returns the trades of every symbol executed at or after
+// since, oldest first.
+func ListTradesSince(pool *pgxpool.Pool, context context.Context, since time.Time) ([]*engine.Trade, error) {
+	rows, err := pool.Query(context, tradeQuery+"WHERE t.executed_at >= $1\nORDER BY t.executed_at, t.id", since)
+	if err != nil {
+		return nil, fmt.Errorf("query trades err: %w", err)
+	}
+	defer rows.Close()
+
+	trades := []*engine.Trade{}
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan trade err: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	return trades, nil
+}
+
+func RetrieveLastTrade(pool *pgxpool.Pool, context context.Context, symbol string) (*engine.Trade, error) {
+	row := pool.QueryRow(context, tradeQuery+"WHERE t.symbol = $1\nORDER BY t.executed_at DESC, t.id DESC LIMIT 1", symbol)
+	trade, err := scanTrade(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTradeNotFound
+		}
+		return nil, fmt.Errorf("query last trade err: %w", err)
+	}
+
+	return trade, nil
+}
+
+func scanTrade(row pgx.Row) (*engine.Trade, error) {
+	var trade engine.Trade
+	err := row.Scan(&trade.ID, &trade.Symbol, &trade.BuyOrderID, &trade.SellOrderID, &trade.MakerOrderID,
+		&trade.TakerOrderID, &trade.TakerSide, &trade.Price, &trade.Quantity, &trade.ExecutedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trade, nil
+}
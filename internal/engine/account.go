@@ -0,0 +1,143 @@
+package engine
+
+import "time"
+
+// OrderUpdateType says what an OrderUpdate reports.
+type OrderUpdateType string
+
+const (
+	OrderUpdateNew       OrderUpdateType = "new"
+	OrderUpdateFill      OrderUpdateType = "fill"
+	OrderUpdateAmended   OrderUpdateType = "amended"
+	OrderUpdateCancelled OrderUpdateType = "cancelled"
+	OrderUpdateExpired   OrderUpdateType = "expired"
+)
+
+// OrderUpdate is an execution report for an order placed on behalf of an
+// account. Order is the order's state right after the reported event, so a
+// taker that fills against several makers gets one fill report per trade,
+// each with the remaining quantity at that point. Fill is set on fill
+// reports only.
+type OrderUpdate struct {
+	Type  OrderUpdateType `json:"type"`
+	Order Order           `json:"order"`
+	Fill  *OrderFill      `json:"fill,omitempty"`
+}
+
+type OrderFill struct {
+	TradeID    string    `json:"trade_id"`
+	Price      float64   `json:"price"`
+	Quantity   float64   `json:"quantity"`
+	Liquidity  string    `json:"liquidity"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// OrderUpdates returns the feed of execution reports for orders that have an
+// Account. Like BookUpdates it must be called before Start, and reports are
+// dropped rather than holding up matching if the reader falls behind.
+func (engine *Engine) OrderUpdates() <-chan OrderUpdate {
+	if engine.orderUpdates == nil {
+		engine.orderUpdates = make(chan OrderUpdate, bookUpdateBuffer)
+	}
+
+	return engine.orderUpdates
+}
+
+func (engine *Engine) publishOrderUpdate(update OrderUpdate) {
+	select {
+	case engine.orderUpdates <- update:
+	default:
+	}
+}
+
+// trackAccountOrders keeps accountOrders, the resting orders that belong to
+// an account, in step with a processed command. Makers leave the book when
+// they fill, so this index is how their fills are traced back to an account.
+func (engine *Engine) trackAccountOrders(order *Order, trades []*Trade) {
+	for _, trade := range trades {
+		if maker, ok := engine.accountOrders[trade.MakerOrderID]; ok && maker.Remaining <= 0 {
+			delete(engine.accountOrders, trade.MakerOrderID)
+		}
+	}
+
+	if order == nil || order.Account == "" {
+		return
+	}
+	if resting(order) {
+		engine.accountOrders[order.ID] = order
+	} else {
+		delete(engine.accountOrders, order.ID)
+	}
+}
+
+func resting(order *Order) bool {
+	return order.Status == OrderOpen || order.Status == OrderPartiallyFilled
+}
+
+func (engine *Engine) indexAccountOrders() {
+	engine.accountOrders = make(map[string]*Order)
+	for _, book := range engine.books {
+		for _, order := range book.orders {
+			if order.Account != "" {
+				engine.accountOrders[order.ID] = order
+			}
+		}
+	}
+}
+
+// executionReports builds the reports of a processed command: those of the
+// command's own order, followed by the fills of any account's makers. It must
+// run before trackAccountOrders forgets the filled makers.
+func (engine *Engine) executionReports(command *Command, order *Order, trades []*Trade) []OrderUpdate {
+	var updates []OrderUpdate
+
+	if order != nil && order.Account != "" {
+		switch {
+		case command.Type == CancelOrderCommand:
+			updates = append(updates, OrderUpdate{Type: OrderUpdateCancelled, Order: *order})
+		case command.Type == AmendOrderCommand && len(trades) == 0:
+			updates = append(updates, OrderUpdate{Type: OrderUpdateAmended, Order: *order})
+		default:
+			// Replay the taker's fills from the state it had before matching.
+			state := *order
+			for _, trade := range trades {
+				state.Remaining += trade.Quantity
+			}
+			state.Status = state.fillStatus()
+
+			first := OrderUpdateNew
+			if command.Type == AmendOrderCommand {
+				first = OrderUpdateAmended
+			}
+			updates = append(updates, OrderUpdate{Type: first, Order: state})
+
+			for _, trade := range trades {
+				state.Remaining -= trade.Quantity
+				state.Status = state.fillStatus()
+				updates = append(updates, OrderUpdate{Type: OrderUpdateFill, Order: state, Fill: newOrderFill(trade, "taker")})
+			}
+
+			if order.Status == OrderExpired {
+				updates = append(updates, OrderUpdate{Type: OrderUpdateExpired, Order: *order})
+			}
+		}
+	}
+
+	for _, trade := range trades {
+		if maker, ok := engine.accountOrders[trade.MakerOrderID]; ok {
+			updates = append(updates, OrderUpdate{Type: OrderUpdateFill, Order: *maker, Fill: newOrderFill(trade, "maker")})
+		}
+	}
+
+	return updates
+}
+
+func newOrderFill(trade *Trade, liquidity string) *OrderFill {
+	return &OrderFill{
+		TradeID:    trade.ID,
+		Price:      trade.Price,
+		Quantity:   trade.Quantity,
+		Liquidity:  liquidity,
+		ExecutedAt: trade.ExecutedAt,
+	}
+}
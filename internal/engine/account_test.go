@@ -0,0 +1,64 @@
+package engine
+
+import "testing"
+
+func drainOrderUpdates(updates <-chan OrderUpdate) []OrderUpdate {
+	var drained []OrderUpdate
+	for {
+		select {
+		case update := <-updates:
+			drained = append(drained, update)
+		default:
+			return drained
+		}
+	}
+}
+
+func TestOrderUpdates_ReportAccountOrders(t *testing.T) {
+	engine := NewEngine(map[string]EventWriter{})
+	updates := engine.OrderUpdates()
+
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "m1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 1, Remaining: 1, Account: "alice"}})
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "m2", Symbol: "SYM", Side: Sell, Price: 101, Quantity: 2, Remaining: 2}})
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "t1", Symbol: "SYM", Side: Buy, Price: 101, Quantity: 2, Remaining: 2, Account: "bob"}})
+
+	got := drainOrderUpdates(updates)
+	want := []struct {
+		kind      OrderUpdateType
+		orderID   string
+		remaining float64
+		status    OrderStatus
+		liquidity string
+	}{
+		{OrderUpdateNew, "m1", 1, OrderOpen, ""},
+		{OrderUpdateNew, "t1", 2, OrderOpen, ""},
+		{OrderUpdateFill, "t1", 1, OrderPartiallyFilled, "taker"},
+		{OrderUpdateFill, "t1", 0, OrderFilled, "taker"},
+		{OrderUpdateFill, "m1", 0, OrderFilled, "maker"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d reports, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		report := got[i]
+		if report.Type != w.kind || report.Order.ID != w.orderID || report.Order.Remaining != w.remaining || report.Order.Status != w.status {
+			t.Errorf("report %d: expected %+v, got %+v", i, w, report)
+		}
+		if (report.Fill == nil) != (w.liquidity == "") || (report.Fill != nil && report.Fill.Liquidity != w.liquidity) {
+			t.Errorf("report %d: expected %q fill, got %+v", i, w.liquidity, report.Fill)
+		}
+	}
+	if _, ok := engine.accountOrders["m1"]; ok {
+		t.Errorf("expected filled maker to leave the account index")
+	}
+
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "r1", Symbol: "SYM", Side: Buy, Price: 90, Quantity: 1, Remaining: 1, Account: "bob"}})
+	engine.process(&Command{Type: CancelOrderCommand, Symbol: "SYM", OrderID: "r1"})
+	got = drainOrderUpdates(updates)
+	if len(got) != 2 || got[1].Type != OrderUpdateCancelled || got[1].Order.Status != OrderCancelled {
+		t.Fatalf("expected new and cancelled reports, got %+v", got)
+	}
+	if len(engine.accountOrders) != 0 {
+		t.Errorf("expected empty account index, got %v", engine.accountOrders)
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import "sort"
+
+// BBO is the top of a book. Prices and sizes are zero for an empty side, and
+// Spread and Mid are only set while both sides are present.
+type BBO struct {
+	Symbol   string  `json:"symbol"`
+	BidPrice float64 `json:"bid_price"`
+	BidQty   float64 `json:"bid_qty"`
+	AskPrice float64 `json:"ask_price"`
+	AskQty   float64 `json:"ask_qty"`
+	Spread   float64 `json:"spread"`
+	Mid      float64 `json:"mid"`
+}
+
+func (orderbook *OrderBook) BBO() BBO {
+	return orderbook.bbo
+}
+
+// refreshBBO recomputes the top of book from the best level on each side.
+// Level volumes are kept up to date as orders enter and leave, so this never
+// walks the book.
+func (orderbook *OrderBook) refreshBBO() {
+	bbo := BBO{Symbol: orderbook.Symbol}
+	if len(orderbook.buysPrices) > 0 {
+		level := orderbook.buys[orderbook.buysPrices[0]]
+		bbo.BidPrice = level.Price
+		bbo.BidQty = level.Volume
+	}
+	if len(orderbook.sellsPrices) > 0 {
+		level := orderbook.sells[orderbook.sellsPrices[0]]
+		bbo.AskPrice = level.Price
+		bbo.AskQty = level.Volume
+	}
+	if bbo.BidPrice > 0 && bbo.AskPrice > 0 {
+		bbo.Spread = bbo.AskPrice - bbo.BidPrice
+		bbo.Mid = (bbo.AskPrice + bbo.BidPrice) / 2
+	}
+
+	orderbook.bbo = bbo
+}
+
+// BBO returns the top of book for a symbol, false if it has no book.
+func (engine *Engine) BBO(symbol string) (BBO, bool) {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+
+	book, ok := engine.books[symbol]
+	if !ok {
+		return BBO{}, false
+	}
+
+	return book.bbo, true
+}
+
+// BBOs returns the top of every book, sorted by symbol.
+func (engine *Engine) BBOs() []BBO {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+
+	bbos := make([]BBO, 0, len(engine.books))
+	for _, book := range engine.books {
+		bbos = append(bbos, book.bbo)
+	}
+	sort.Slice(bbos, func(i, j int) bool { return bbos[i].Symbol < bbos[j].Symbol })
+
+	return bbos
+}
@@ -0,0 +1,32 @@
+package engine
+
+import "testing"
+
+func TestBBO_FollowsBookChanges(t *testing.T) {
+	ob := NewOrderBook("SYM")
+
+	ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 98, Quantity: 2, Remaining: 2})
+	ob.MatchIncoming(&Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 98, Quantity: 1, Remaining: 1})
+	ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 102, Quantity: 4, Remaining: 4})
+
+	want := BBO{Symbol: "SYM", BidPrice: 98, BidQty: 3, AskPrice: 102, AskQty: 4, Spread: 4, Mid: 100}
+	if got := ob.BBO(); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	ob.MatchIncoming(&Order{ID: "t1", Symbol: "SYM", Side: Buy, Price: 102, Quantity: 1, Remaining: 1})
+	if got := ob.BBO(); got.AskQty != 3 {
+		t.Errorf("expected ask qty 3 after partial fill, got %v", got.AskQty)
+	}
+
+	ob.Amend("b1", 98, 1)
+	if got := ob.BBO(); got.BidQty != 2 {
+		t.Errorf("expected bid qty 2 after amend, got %v", got.BidQty)
+	}
+
+	ob.Cancel("s1")
+	want = BBO{Symbol: "SYM", BidPrice: 98, BidQty: 2}
+	if got := ob.BBO(); got != want {
+		t.Errorf("expected %+v after cancel, got %+v", want, got)
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// ChecksumDepth is the number of price levels per side that a book checksum
+// covers.
+const ChecksumDepth = 25
+
+// Checksum is the CRC32 (IEEE) of the book's top ChecksumDepth raw levels on
+// each side, as ChecksumLevels computes it.
+func (orderbook *OrderBook) Checksum() uint32 {
+	return ChecksumLevels(orderbook.checksumLevels())
+}
+
+// ChecksumLevels is the book checksum of raw levels listed best price first.
+// Only the first ChecksumDepth levels of each side count. The checksummed
+// string lists them alternating bid and ask level by level, as price:qty
+// pairs joined by ':'. Once one side runs out only the other side's levels
+// follow. Numbers are written in plain decimal with the fewest digits that
+// round-trip, without an exponent or trailing zeros. For bids 100 x 1.5 and
+// 99 x 2 and an ask 101 x 3 that is "100:1.5:101:3:99:2". An empty book has
+// checksum 0.
+func ChecksumLevels(bids []DepthLevel, asks []DepthLevel) uint32 {
+	bids = bids[:min(len(bids), ChecksumDepth)]
+	asks = asks[:min(len(asks), ChecksumDepth)]
+
+	var parts []string
+	for i := 0; i < len(bids) || i < len(asks); i++ {
+		if i < len(bids) {
+			parts = append(parts, formatChecksumLevel(bids[i]))
+		}
+		if i < len(asks) {
+			parts = append(parts, formatChecksumLevel(asks[i]))
+		}
+	}
+
+	return crc32.ChecksumIEEE([]byte(strings.Join(parts, ":")))
+}
+
+// rawLevels are the top levels of a book that its checksum covers.
+type rawLevels struct {
+	bids []DepthLevel
+	asks []DepthLevel
+}
+
+func (levels rawLevels) checksum() uint32 {
+	return ChecksumLevels(levels.bids, levels.asks)
+}
+
+// checksumLevels copies the raw levels a checksum covers, so that the
+// checksum itself can be worked out after the engine lock is released.
+func (orderbook *OrderBook) checksumLevels() (bids []DepthLevel, asks []DepthLevel) {
+	return topLevels(orderbook.buys, orderbook.buysPrices), topLevels(orderbook.sells, orderbook.sellsPrices)
+}
+
+// topLevels lists the top levels of one side. Levels emptied in the middle of
+// matching are still listed in prices until the match moves on, so they are
+// skipped here.
+func topLevels(levels map[float64]*PriceLevel, prices []float64) []DepthLevel {
+	var result []DepthLevel
+	for _, price := range prices {
+		if len(result) >= ChecksumDepth {
+			break
+		}
+
+		level := levels[price]
+		if level == nil || len(level.Orders) == 0 {
+			continue
+		}
+		result = append(result, DepthLevel{Price: price, Qty: level.Volume})
+	}
+
+	return result
+}
+
+func formatChecksumLevel(level DepthLevel) string {
+	return strconv.FormatFloat(level.Price, 'f', -1, 64) + ":" + strconv.FormatFloat(level.Qty, 'f', -1, 64)
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestChecksum_CanonicalString(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	if got := ob.Checksum(); got != 0 {
+		t.Fatalf("expected empty book checksum 0, got %d", got)
+	}
+
+	ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 99, Quantity: 2, Remaining: 2})
+	ob.MatchIncoming(&Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+	ob.MatchIncoming(&Order{ID: "b3", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 0.5, Remaining: 0.5})
+	ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 101, Quantity: 3, Remaining: 3})
+
+	if want := crc32.ChecksumIEEE([]byte("100:1.5:101:3:99:2")); ob.Checksum() != want {
+		t.Errorf("expected checksum %d, got %d", want, ob.Checksum())
+	}
+}
+
+func TestChecksumLevels_CoversTopLevelsOnly(t *testing.T) {
+	var bids, asks []DepthLevel
+	want := []string{}
+	for i := 0; i < ChecksumDepth+5; i++ {
+		bids = append(bids, DepthLevel{Price: float64(100 - i), Qty: 1})
+		if i < ChecksumDepth {
+			want = append(want, strconv.Itoa(100-i)+":1")
+		}
+	}
+	asks = append(asks, DepthLevel{Price: 101, Qty: 2, Total: 2})
+	want = append(want[:1], append([]string{"101:2"}, want[1:]...)...)
+
+	if sum := crc32.ChecksumIEEE([]byte(strings.Join(want, ":"))); ChecksumLevels(bids, asks) != sum {
+		t.Errorf("expected the checksum of the top %d levels", ChecksumDepth)
+	}
+}
+
+func TestDepth_ChecksumMatchesBook(t *testing.T) {
+	engine := NewEngine(map[string]EventWriter{})
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1}})
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 101, Quantity: 2, Remaining: 2}})
+
+	depth, _ := engine.Depth("SYM", DepthOptions{Depth: 1, Aggregation: 10})
+	if want := crc32.ChecksumIEEE([]byte("100:1:101:2")); depth.Checksum != want || engine.Depths(DepthOptions{Depth: 1})["SYM"].Checksum != want {
+		t.Errorf("expected snapshots to carry the raw book checksum %d, got %d", want, depth.Checksum)
+	}
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Clock and IDGenerator are called from the matching loop and from API
+// goroutines submitting orders, so implementations must be safe for
+// concurrent use.
+type Clock interface {
+	Now() time.Time
+}
+
+type IDGenerator interface {
+	NewID() string
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+// ManualClock only moves when told to, for tests and simulations.
+type ManualClock struct {
+	now time.Time
+	mu  sync.Mutex
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start.UTC()}
+}
+
+func (clock *ManualClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *ManualClock) Set(now time.Time) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = now.UTC()
+}
+
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+// SequentialIDs hands out prefix1, prefix2, ... in call order.
+type SequentialIDs struct {
+	prefix string
+	next   atomic.Uint64
+}
+
+func NewSequentialIDs(prefix string) *SequentialIDs {
+	return &SequentialIDs{prefix: prefix}
+}
+
+func (ids *SequentialIDs) NewID() string {
+	return fmt.Sprintf("%s%d", ids.prefix, ids.next.Add(1))
+}
@@ -0,0 +1,26 @@
+package engine
+
+type CommandType string
+
+const (
+	NewOrderCommand    CommandType = "new"
+	CancelOrderCommand CommandType = "cancel"
+	AmendOrderCommand  CommandType = "amend"
+)
+
+// Command is a single engine input. Commands are applied strictly in Seq
+// order, which is what makes replaying them rebuild the same books.
+type Command struct {
+	Seq      uint64      `json:"seq"`
+	Type     CommandType `json:"type"`
+	Order    *Order      `json:"order,omitempty"`
+	Symbol   string      `json:"symbol,omitempty"`
+	OrderID  string      `json:"order_id,omitempty"`
+	Price    float64     `json:"price,omitempty"`
+	Quantity float64     `json:"quantity,omitempty"`
+}
+
+type pendingCommand struct {
+	command *Command
+	done    chan error
+}
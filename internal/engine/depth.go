@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DepthOptions controls an aggregated depth snapshot. Aggregation groups
+// levels into buckets of that price increment, rounding bids down and asks up
+// so a bucket never shows a better price than the orders in it; zero keeps
+// the raw levels. Cumulative adds a running total of quantity from the top of
+// each side, as depth charts plot it.
+type DepthOptions struct {
+	Depth       int
+	Aggregation float64
+	Cumulative  bool
+}
+
+// DepthLevel is one aggregated price level. Total is only set for cumulative
+// snapshots.
+type DepthLevel struct {
+	Price float64 `json:"price"`
+	Qty   float64 `json:"qty"`
+	Total float64 `json:"total,omitempty"`
+}
+
+// Depth is Snapshot with price grouping and running totals. Depth counts the
+// levels returned, so with aggregation it is a number of buckets.
+func (orderbook *OrderBook) Depth(options DepthOptions) (bids []DepthLevel, asks []DepthLevel) {
+	bids = depthLevels(orderbook.buys, orderbook.buysPrices, options, math.Floor)
+	asks = depthLevels(orderbook.sells, orderbook.sellsPrices, options, math.Ceil)
+	return
+}
+
+// BookDepth is the aggregated depth of one book as of update Seq. Checksum
+// always covers the raw levels, whatever the aggregation.
+type BookDepth struct {
+	Symbol   string       `json:"symbol"`
+	Seq      uint64       `json:"seq"`
+	Checksum uint32       `json:"checksum"`
+	Bids     []DepthLevel `json:"bids"`
+	Asks     []DepthLevel `json:"asks"`
+}
+
+// bookDepth builds the depth of the book without its checksum, and copies the
+// levels the checksum covers so it can be computed after the engine lock is
+// released.
+func (orderbook *OrderBook) bookDepth(options DepthOptions) (BookDepth, rawLevels) {
+	depth := BookDepth{Symbol: orderbook.Symbol, Seq: orderbook.updateSeq}
+	depth.Bids, depth.Asks = orderbook.Depth(options)
+	if depth.Bids == nil {
+		depth.Bids = []DepthLevel{}
+	}
+	if depth.Asks == nil {
+		depth.Asks = []DepthLevel{}
+	}
+
+	var raw rawLevels
+	raw.bids, raw.asks = orderbook.checksumLevels()
+	return depth, raw
+}
+
+// Depth returns the aggregated depth of a symbol, false if it has no book.
+func (engine *Engine) Depth(symbol string, options DepthOptions) (BookDepth, bool) {
+	engine.mu.RLock()
+	book, ok := engine.books[symbol]
+	if !ok {
+		engine.mu.RUnlock()
+		return BookDepth{Symbol: symbol, Bids: []DepthLevel{}, Asks: []DepthLevel{}}, false
+	}
+	depth, raw := book.bookDepth(options)
+	engine.mu.RUnlock()
+
+	depth.Checksum = raw.checksum()
+	return depth, true
+}
+
+// Depths returns the aggregated depth of every book, keyed by symbol.
+func (engine *Engine) Depths(options DepthOptions) map[string]BookDepth {
+	engine.mu.RLock()
+	depths := make(map[string]BookDepth, len(engine.books))
+	raws := make(map[string]rawLevels, len(engine.books))
+	for symbol, book := range engine.books {
+		depths[symbol], raws[symbol] = book.bookDepth(options)
+	}
+	engine.mu.RUnlock()
+
+	for symbol, raw := range raws {
+		depth := depths[symbol]
+		depth.Checksum = raw.checksum()
+		depths[symbol] = depth
+	}
+
+	return depths
+}
+
+func depthLevels(levels map[float64]*PriceLevel, prices []float64, options DepthOptions, round func(float64) float64) []DepthLevel {
+	var result []DepthLevel
+	decimals := stepDecimals(options.Aggregation)
+	total := 0.0
+	for _, price := range prices {
+		volume := levels[price].Volume
+		total += volume
+
+		if options.Aggregation > 0 {
+			price = roundTo(round(price/options.Aggregation)*options.Aggregation, decimals)
+		}
+
+		if last := len(result) - 1; last >= 0 && result[last].Price == price {
+			result[last].Qty += volume
+			if options.Cumulative {
+				result[last].Total = total
+			}
+			continue
+		}
+		if len(result) >= options.Depth {
+			break
+		}
+
+		level := DepthLevel{Price: price, Qty: volume}
+		if options.Cumulative {
+			level.Total = total
+		}
+		result = append(result, level)
+	}
+
+	return result
+}
+
+// stepDecimals is the number of decimal places in step, used to strip the
+// floating point noise that bucketing leaves on prices such as 0.1 steps.
+func stepDecimals(step float64) int {
+	text := strconv.FormatFloat(step, 'f', -1, 64)
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		return len(text) - i - 1
+	}
+
+	return 0
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
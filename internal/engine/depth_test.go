@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDepth_AggregatesAndAccumulates(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	for i, price := range []float64{99.95, 99.91, 99.84} {
+		ob.MatchIncoming(&Order{ID: string(rune('a' + i)), Symbol: "SYM", Side: Buy, Price: price, Quantity: 1, Remaining: 1})
+	}
+	for i, price := range []float64{100.01, 100.09, 100.12} {
+		ob.MatchIncoming(&Order{ID: string(rune('x' + i)), Symbol: "SYM", Side: Sell, Price: price, Quantity: 2, Remaining: 2})
+	}
+
+	bids, asks := ob.Depth(DepthOptions{Depth: 10, Aggregation: 0.1, Cumulative: true})
+
+	wantBids := []DepthLevel{{Price: 99.9, Qty: 2, Total: 2}, {Price: 99.8, Qty: 1, Total: 3}}
+	wantAsks := []DepthLevel{{Price: 100.1, Qty: 4, Total: 4}, {Price: 100.2, Qty: 2, Total: 6}}
+	if !reflect.DeepEqual(bids, wantBids) {
+		t.Errorf("expected bids %v, got %v", wantBids, bids)
+	}
+	if !reflect.DeepEqual(asks, wantAsks) {
+		t.Errorf("expected asks %v, got %v", wantAsks, asks)
+	}
+
+	bids, _ = ob.Depth(DepthOptions{Depth: 1, Aggregation: 0.1})
+	if want := []DepthLevel{{Price: 99.9, Qty: 2}}; !reflect.DeepEqual(bids, want) {
+		t.Errorf("expected bids %v with depth 1, got %v", want, bids)
+	}
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+type BookAction string
+
+const (
+	BookOrderOpened  BookAction = "open"
+	BookOrderChanged BookAction = "change"
+	BookOrderRemoved BookAction = "remove"
+)
+
+// BookUpdate is one change to a single resting order. Seq counts the updates
+// of a book, so a client holding an L3 snapshot applies only the updates with
+// a larger Seq and can spot a gap in the feed. LevelQty is the total left at
+// the order's price afterwards, zero once the level is gone.
+type BookUpdate struct {
+	Seq       uint64     `json:"seq"`
+	Symbol    string     `json:"symbol"`
+	Action    BookAction `json:"action"`
+	OrderID   string     `json:"order_id"`
+	Side      Side       `json:"side"`
+	Price     float64    `json:"price"`
+	Remaining float64    `json:"remaining"`
+	LevelQty  float64    `json:"level_qty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LevelUpdate is the level-2 view of a BookUpdate: the new aggregate
+// quantity at one price, with the same Seq. The engine leaves Checksum unset
+// so that matching doesn't pay for it; whoever follows the levels fills it in
+// with ChecksumLevels.
+type LevelUpdate struct {
+	Seq      uint64  `json:"seq"`
+	Side     Side    `json:"side"`
+	Price    float64 `json:"price"`
+	Qty      float64 `json:"qty"`
+	Checksum uint32  `json:"checksum"`
+}
+
+func (update BookUpdate) Level() LevelUpdate {
+	return LevelUpdate{Seq: update.Seq, Side: update.Side, Price: update.Price, Qty: update.LevelQty}
+}
+
+type L3Order struct {
+	ID        string    `json:"id"`
+	Remaining float64   `json:"remaining"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type L3Level struct {
+	Price  float64   `json:"price"`
+	Orders []L3Order `json:"orders"`
+}
+
+type L3Book struct {
+	Symbol string    `json:"symbol"`
+	Seq    uint64    `json:"seq"`
+	Bids   []L3Level `json:"bids"`
+	Asks   []L3Level `json:"asks"`
+}
+
+const bookUpdateBuffer = 100000
+
+var maskKey = func() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}()
+
+// MaskOrderID replaces an order ID with a token that is stable for the life of
+// the process, so a masked snapshot and a masked feed still line up, but that
+// can't be traced back to the order its owner submitted.
+func MaskOrderID(orderID string) string {
+	mac := hmac.New(sha256.New, maskKey)
+	mac.Write([]byte(orderID))
+	return hex.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// Mask returns a copy of the update with its order ID masked.
+func (update BookUpdate) Mask() BookUpdate {
+	update.OrderID = MaskOrderID(update.OrderID)
+	return update
+}
+
+// Mask returns a copy of the snapshot with every order ID masked.
+func (book L3Book) Mask() L3Book {
+	book.Bids = maskLevels(book.Bids)
+	book.Asks = maskLevels(book.Asks)
+	return book
+}
+
+func maskLevels(levels []L3Level) []L3Level {
+	masked := make([]L3Level, len(levels))
+	for i, level := range levels {
+		orders := make([]L3Order, len(level.Orders))
+		for j, order := range level.Orders {
+			order.ID = MaskOrderID(order.ID)
+			orders[j] = order
+		}
+		masked[i] = L3Level{Price: level.Price, Orders: orders}
+	}
+
+	return masked
+}
+
+// L3Snapshot lists the individual orders of up to depth price levels on each
+// side, in queue order, together with the Seq of the last update applied.
+func (orderbook *OrderBook) L3Snapshot(depth int) L3Book {
+	return L3Book{
+		Symbol: orderbook.Symbol,
+		Seq:    orderbook.updateSeq,
+		Bids:   l3Levels(orderbook.buys, orderbook.buysPrices, depth),
+		Asks:   l3Levels(orderbook.sells, orderbook.sellsPrices, depth),
+	}
+}
+
+func l3Levels(levels map[float64]*PriceLevel, prices []float64, depth int) []L3Level {
+	result := []L3Level{}
+	for i, price := range prices {
+		if i >= depth {
+			break
+		}
+
+		level := L3Level{Price: price, Orders: make([]L3Order, 0, len(levels[price].Orders))}
+		for _, order := range levels[price].Orders {
+			level.Orders = append(level.Orders, L3Order{ID: order.ID, Remaining: order.Remaining, CreatedAt: order.CreatedAt})
+		}
+		result = append(result, level)
+	}
+
+	return result
+}
+
+func (engine *Engine) L3Snapshot(symbol string, depth int) L3Book {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+
+	book, ok := engine.books[symbol]
+	if !ok {
+		return L3Book{Symbol: symbol, Bids: []L3Level{}, Asks: []L3Level{}}
+	}
+
+	return book.L3Snapshot(depth)
+}
+
+// BookUpdates returns the feed of per-order book changes. It must be called
+// before Start. The matching loop never waits on the feed: if the reader falls
+// behind by more than the buffer, updates are dropped and the reader sees a
+// gap in Seq.
+func (engine *Engine) BookUpdates() <-chan BookUpdate {
+	if engine.bookUpdates == nil {
+		engine.bookUpdates = make(chan BookUpdate, bookUpdateBuffer)
+	}
+
+	return engine.bookUpdates
+}
+
+func (engine *Engine) publishBookUpdate(update BookUpdate) {
+	if engine.bookUpdates == nil {
+		return
+	}
+
+	select {
+	case engine.bookUpdates <- update:
+	default:
+	}
+}
+
+func (orderbook *OrderBook) emit(action BookAction, order *Order) {
+	orderbook.updateSeq++
+	if orderbook.onUpdate == nil {
+		return
+	}
+
+	levels := orderbook.sells
+	if order.Side == Buy {
+		levels = orderbook.buys
+	}
+	levelQty := 0.0
+	if level, ok := levels[order.Price]; ok && len(level.Orders) > 0 {
+		levelQty = level.Volume
+	}
+
+	orderbook.onUpdate(BookUpdate{
+		Seq:       orderbook.updateSeq,
+		Symbol:    orderbook.Symbol,
+		Action:    action,
+		OrderID:   order.ID,
+		Side:      order.Side,
+		Price:     order.Price,
+		Remaining: order.Remaining,
+		LevelQty:  levelQty,
+		CreatedAt: order.CreatedAt,
+	})
+}
@@ -0,0 +1,63 @@
+package engine
+
+import "testing"
+
+func TestL3Snapshot_KeepsQueueOrder(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 99, Quantity: 1, Remaining: 1})
+	ob.MatchIncoming(&Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 99, Quantity: 2, Remaining: 2})
+	ob.MatchIncoming(&Order{ID: "b3", Symbol: "SYM", Side: Buy, Price: 98, Quantity: 3, Remaining: 3})
+
+	snapshot := ob.L3Snapshot(1)
+	if snapshot.Seq != 3 {
+		t.Errorf("expected seq 3, got %d", snapshot.Seq)
+	}
+	if len(snapshot.Bids) != 1 || len(snapshot.Asks) != 0 {
+		t.Fatalf("expected one bid level and no asks, got %+v", snapshot)
+	}
+	orders := snapshot.Bids[0].Orders
+	if len(orders) != 2 || orders[0].ID != "b1" || orders[1].ID != "b2" || orders[1].Remaining != 2 {
+		t.Errorf("unexpected orders at 99: %+v", orders)
+	}
+
+	masked := snapshot.Mask()
+	if masked.Bids[0].Orders[0].ID == "b1" || masked.Bids[0].Orders[0].ID != MaskOrderID("b1") {
+		t.Errorf("expected masked order ID, got %q", masked.Bids[0].Orders[0].ID)
+	}
+	if snapshot.Bids[0].Orders[0].ID != "b1" {
+		t.Errorf("masking changed the original snapshot")
+	}
+}
+
+func TestBookUpdates_FollowMatching(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	var updates []BookUpdate
+	ob.onUpdate = func(update BookUpdate) { updates = append(updates, update) }
+
+	ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 2, Remaining: 2})
+	ob.MatchIncoming(&Order{ID: "s2", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 2, Remaining: 2})
+	ob.MatchIncoming(&Order{ID: "t1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 3, Remaining: 3})
+	ob.Cancel("s2")
+
+	want := []struct {
+		action    BookAction
+		orderID   string
+		remaining float64
+		levelQty  float64
+	}{
+		{BookOrderOpened, "s1", 2, 2},
+		{BookOrderOpened, "s2", 2, 4},
+		{BookOrderRemoved, "s1", 0, 2},
+		{BookOrderChanged, "s2", 1, 1},
+		{BookOrderRemoved, "s2", 1, 0},
+	}
+	if len(updates) != len(want) {
+		t.Fatalf("expected %d updates, got %+v", len(want), updates)
+	}
+	for i, w := range want {
+		got := updates[i]
+		if got.Seq != uint64(i+1) || got.Action != w.action || got.OrderID != w.orderID || got.Remaining != w.remaining || got.LevelQty != w.levelQty {
+			t.Errorf("update %d: expected %v %s %v at level %v, got %+v", i, w.action, w.orderID, w.remaining, w.levelQty, got)
+		}
+	}
+}
@@ -2,11 +2,31 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"sync"
 )
 
+// ErrStopped is returned for commands that reach a stopped engine. They are
+// neither journaled nor matched. A command that was journaled before the
+// engine stopped is accepted instead, and is matched when the journal is
+// replayed on the next start.
+var ErrStopped = errors.New("engine stopped")
+
 type Engine struct {
 	books          map[string]*OrderBook
-	orderChannel   chan *Order
+	commandChannel chan *Command
+	journalChannel chan *pendingCommand
+	journal        Journal
+	stopped        <-chan struct{}
+	journalStopped chan struct{}
+	seq            uint64
+	clock          Clock
+	ids            IDGenerator
+	mu             sync.RWMutex
+	bookUpdates    chan BookUpdate
+	orderUpdates   chan OrderUpdate
+	tradeFeeds     []chan []*Trade
+	accountOrders  map[string]*Order
 	orderPublisher EventWriter
 	tradePublisher EventWriter
 }
@@ -15,16 +35,28 @@ type EventWriter interface {
 	Publish(eventType string, payload any) error
 }
 
+// Journal durably records engine commands before they are applied. Write
+// must not return until the whole batch is on stable storage.
+type Journal interface {
+	Write(commands []*Command) error
+}
+
 const (
 	SnapshotTopic = "orderbook_snapshot"
 	OrderTopic    = "order_events"
 	TradeTopic    = "trade_events"
 )
 
+const journalBatchSize = 512
+
 func NewEngine(eventPublishers map[string]EventWriter) *Engine {
 	return &Engine{
 		books:          make(map[string]*OrderBook),
-		orderChannel:   make(chan *Order, 100000),
+		commandChannel: make(chan *Command, 100000),
+		journalChannel: make(chan *pendingCommand, 100000),
+		clock:          systemClock{},
+		ids:            uuidGenerator{},
+		accountOrders:  make(map[string]*Order),
 		orderPublisher: eventPublishers[OrderTopic],
 		tradePublisher: eventPublishers[TradeTopic],
 	}
@@ -32,23 +64,125 @@ func NewEngine(eventPublishers map[string]EventWriter) *Engine {
 
 func (engine *Engine) Setup(orderbooks map[string]*OrderBook) {
 	engine.books = orderbooks
+	for _, book := range orderbooks {
+		book.UseClock(engine.clock)
+		book.UseIDGenerator(engine.ids)
+		book.onUpdate = engine.publishBookUpdate
+	}
+	engine.indexAccountOrders()
+}
+
+// UseClock replaces the time source for order acceptance and trade execution,
+// including on books that already exist.
+func (engine *Engine) UseClock(clock Clock) {
+	engine.clock = clock
+	for _, book := range engine.books {
+		book.UseClock(clock)
+	}
+}
+
+func (engine *Engine) Clock() Clock {
+	return engine.clock
+}
+
+// UseIDGenerator replaces the source of order and trade IDs, including on
+// books that already exist.
+func (engine *Engine) UseIDGenerator(ids IDGenerator) {
+	engine.ids = ids
+	for _, book := range engine.books {
+		book.UseIDGenerator(ids)
+	}
+}
+
+func (engine *Engine) UseJournal(journal Journal) {
+	engine.journal = journal
+}
+
+// JournalBooks records every resting order as a new-order command so that a
+// journal started on top of books loaded from elsewhere can rebuild them.
+func (engine *Engine) JournalBooks() error {
+	var commands []*Command
+	for _, book := range engine.books {
+		for _, order := range book.RestingOrders() {
+			engine.seq++
+			commands = append(commands, &Command{Seq: engine.seq, Type: NewOrderCommand, Order: order})
+		}
+	}
+
+	return engine.journal.Write(commands)
 }
 
 func (engine *Engine) Start(ctx context.Context) {
+	engine.stopped = ctx.Done()
+	if engine.journal != nil {
+		engine.startJournalWriter(ctx)
+	}
+
 	go func() {
 		for {
 			select {
-			case order := <-engine.orderChannel:
+			case command := <-engine.commandChannel:
+				engine.process(command)
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
 
-				orderbook := engine.GetBook(order.Symbol)
-				trades := orderbook.MatchIncoming(order)
+// startJournalWriter drains pending commands in batches so that a single
+// fsync covers every order that arrived while the previous one was running.
+// Commands are handed to the matching loop only after they are durable. The
+// writer finishes the batch it is writing before it stops, and closes
+// journalStopped once no command it took will be answered.
+func (engine *Engine) startJournalWriter(ctx context.Context) {
+	engine.journalStopped = make(chan struct{})
+	go func() {
+		defer close(engine.journalStopped)
 
-				if len(trades) > 0 {
-					go engine.publishTradeEvent("order_matched", trades)
+		batch := make([]*pendingCommand, 0, journalBatchSize)
+		commands := make([]*Command, 0, journalBatchSize)
+		for {
+			select {
+			case pending := <-engine.journalChannel:
+				if ctx.Err() != nil {
+					pending.done <- ErrStopped
+					return
+				}
+				batch = append(batch[:0], pending)
+			drain:
+				for len(batch) < journalBatchSize {
+					select {
+					case pending := <-engine.journalChannel:
+						batch = append(batch, pending)
+					default:
+						break drain
+					}
+				}
+
+				commands = commands[:0]
+				for _, pending := range batch {
+					engine.seq++
+					pending.command.Seq = engine.seq
+					commands = append(commands, pending.command)
 				}
 
-				if order.Remaining > 0 && order.Price > 0 {
-					go engine.publishOrderEvent("order_added", order)
+				err := engine.journal.Write(commands)
+				if err != nil {
+					engine.seq -= uint64(len(batch))
+				}
+
+				// Once written a command stands, so it is acknowledged even if
+				// the matching loop has stopped before it could be handed over.
+				for _, pending := range batch {
+					if err == nil {
+						select {
+						case engine.commandChannel <- pending.command:
+						case <-ctx.Done():
+						}
+					}
+					pending.done <- err
 				}
 
 			case <-ctx.Done():
@@ -58,8 +192,140 @@ func (engine *Engine) Start(ctx context.Context) {
 	}()
 }
 
-func (engine *Engine) Submit(order *Order) {
-	engine.orderChannel <- order
+// Submit accepts a new order, assigning its ID if it has none and stamping
+// CreatedAt with the engine clock.
+func (engine *Engine) Submit(order *Order) error {
+	if order.ID == "" {
+		order.ID = engine.ids.NewID()
+	}
+	order.CreatedAt = engine.clock.Now()
+
+	return engine.dispatch(&Command{Type: NewOrderCommand, Order: order})
+}
+
+// NewOrderID returns a fresh ID for callers that need to know an order's ID
+// before submitting it.
+func (engine *Engine) NewOrderID() string {
+	return engine.ids.NewID()
+}
+
+func (engine *Engine) Cancel(symbol string, orderID string) error {
+	return engine.dispatch(&Command{Type: CancelOrderCommand, Symbol: symbol, OrderID: orderID})
+}
+
+// Amend changes the price and total quantity of a resting order. Reducing the
+// quantity at the same price keeps queue priority; anything else re-queues it.
+func (engine *Engine) Amend(symbol string, orderID string, price float64, quantity float64) error {
+	return engine.dispatch(&Command{Type: AmendOrderCommand, Symbol: symbol, OrderID: orderID, Price: price, Quantity: quantity})
+}
+
+// dispatch gives up with ErrStopped once the engine is stopped, since nothing
+// is left to drain the channels. A journaled command waits for the writer's
+// answer while the writer runs, so a command it wrote is never reported as
+// stopped.
+func (engine *Engine) dispatch(command *Command) error {
+	if engine.journal == nil {
+		select {
+		case engine.commandChannel <- command:
+			return nil
+		case <-engine.stopped:
+			return ErrStopped
+		}
+	}
+
+	pending := &pendingCommand{command: command, done: make(chan error, 1)}
+	select {
+	case engine.journalChannel <- pending:
+	case <-engine.journalStopped:
+		return ErrStopped
+	}
+
+	select {
+	case err := <-pending.done:
+		return err
+	case <-engine.journalStopped:
+		// the writer answers every command it took before it stops
+		select {
+		case err := <-pending.done:
+			return err
+		default:
+			return ErrStopped
+		}
+	}
+}
+
+// Replay applies a previously journaled command without publishing events.
+func (engine *Engine) Replay(command *Command) error {
+	order, trades := engine.apply(command)
+	engine.trackAccountOrders(order, trades)
+	if command.Seq > engine.seq {
+		engine.seq = command.Seq
+	}
+
+	return nil
+}
+
+func (engine *Engine) process(command *Command) {
+	if command.Seq == 0 {
+		engine.seq++
+		command.Seq = engine.seq
+	}
+
+	engine.mu.Lock()
+	order, trades := engine.apply(command)
+	var published Order
+	if order != nil {
+		published = *order
+	}
+	var reports []OrderUpdate
+	if engine.orderUpdates != nil {
+		reports = engine.executionReports(command, order, trades)
+	}
+	engine.trackAccountOrders(order, trades)
+	engine.mu.Unlock()
+
+	for _, report := range reports {
+		engine.publishOrderUpdate(report)
+	}
+
+	if len(trades) > 0 {
+		engine.publishTrades(trades)
+		go engine.publishTradeEvent("order_matched", trades)
+	}
+
+	if order == nil {
+		return
+	}
+
+	switch command.Type {
+	case NewOrderCommand:
+		switch published.Status {
+		case OrderFilled:
+			go engine.publishOrderEvent("order_filled", &published)
+		case OrderExpired:
+			go engine.publishOrderEvent("order_expired", &published)
+		default:
+			go engine.publishOrderEvent("order_added", &published)
+		}
+	case CancelOrderCommand:
+		go engine.publishOrderEvent("order_cancelled", &published)
+	case AmendOrderCommand:
+		go engine.publishOrderEvent("order_amended", &published)
+	}
+}
+
+func (engine *Engine) apply(command *Command) (*Order, []*Trade) {
+	switch command.Type {
+	case NewOrderCommand:
+		order := command.Order
+		return order, engine.book(order.Symbol).MatchIncoming(order)
+	case CancelOrderCommand:
+		return engine.book(command.Symbol).Cancel(command.OrderID), nil
+	case AmendOrderCommand:
+		return engine.book(command.Symbol).Amend(command.OrderID, command.Price, command.Quantity)
+	}
+
+	return nil, nil
 }
 
 func (e *Engine) publishOrderEvent(eventType string, payload any) {
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type memoryJournal struct {
+	commands []*Command
+}
+
+func (journal *memoryJournal) Write(commands []*Command) error {
+	journal.commands = append(journal.commands, commands...)
+	return nil
+}
+
+func TestSubmit_JournalsBeforeMatching(t *testing.T) {
+	engine := NewEngine(map[string]EventWriter{})
+	journal := &memoryJournal{}
+	engine.UseJournal(journal)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine.Start(ctx)
+
+	if err := engine.Submit(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(journal.commands) != 1 || journal.commands[0].Seq != 1 {
+		t.Fatalf("expected the order journaled as seq 1, got %+v", journal.commands)
+	}
+}
+
+// gatedJournal holds every write until release is closed.
+type gatedJournal struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (journal *gatedJournal) Write([]*Command) error {
+	journal.writing <- struct{}{}
+	<-journal.release
+	return nil
+}
+
+func TestSubmit_ShutdownDuringJournalWrite(t *testing.T) {
+	engine := NewEngine(map[string]EventWriter{})
+	journal := &gatedJournal{writing: make(chan struct{}, 1), release: make(chan struct{})}
+	engine.UseJournal(journal)
+	ctx, cancel := context.WithCancel(context.Background())
+	engine.Start(ctx)
+
+	written := make(chan error, 1)
+	go func() {
+		written <- engine.Submit(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+	}()
+	<-journal.writing
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- engine.Submit(&Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+	}()
+	for len(engine.journalChannel) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	close(journal.release)
+
+	for _, result := range []struct {
+		name string
+		got  chan error
+		want error
+	}{
+		{"journaled order", written, nil},
+		{"order queued behind the write", queued, ErrStopped},
+	} {
+		select {
+		case err := <-result.got:
+			if !errors.Is(err, result.want) {
+				t.Errorf("%s: expected %v, got %v", result.name, result.want, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: Submit blocked after shutdown", result.name)
+		}
+	}
+
+	if err := engine.Cancel("SYM", "b1"); !errors.Is(err, ErrStopped) {
+		t.Errorf("expected ErrStopped for a command sent after shutdown, got %v", err)
+	}
+}
@@ -4,12 +4,37 @@ import (
 	"time"
 )
 
+type OrderStatus string
+
+const (
+	OrderOpen            OrderStatus = "open"
+	OrderPartiallyFilled OrderStatus = "partially_filled"
+	OrderFilled          OrderStatus = "filled"
+	OrderCancelled       OrderStatus = "cancelled"
+	OrderExpired         OrderStatus = "expired"
+)
+
 type Order struct {
-	ID        string    `json:"id"`
-	Symbol    string    `json:"symbol"`
-	Side      Side      `json:"side"`
-	Price     float64   `json:"price"`
-	Quantity  float64   `json:"quantity"`
-	Remaining float64   `json:"remaining"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string      `json:"id"`
+	Symbol    string      `json:"symbol"`
+	Side      Side        `json:"side"`
+	Price     float64     `json:"price"`
+	Quantity  float64     `json:"quantity"`
+	Remaining float64     `json:"remaining"`
+	Status    OrderStatus `json:"status,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	// Account is the owner of an order placed with an API key; it is empty
+	// for anonymous orders.
+	Account string `json:"account,omitempty"`
+}
+
+func (order *Order) fillStatus() OrderStatus {
+	if order.Remaining <= 0 {
+		return OrderFilled
+	}
+	if order.Remaining < order.Quantity {
+		return OrderPartiallyFilled
+	}
+
+	return OrderOpen
 }
@@ -3,9 +3,6 @@ package engine
 import (
 	"math"
 	"sort"
-	"time"
-
-	"github.com/google/uuid"
 )
 
 type OrderBook struct {
@@ -14,27 +11,104 @@ type OrderBook struct {
 	buysPrices  []float64
 	sells       map[float64]*PriceLevel
 	sellsPrices []float64
+	orders      map[string]*Order
+	bbo         BBO
+	updateSeq   uint64
+	onUpdate    func(BookUpdate)
+	clock       Clock
+	ids         IDGenerator
 }
 
 func NewOrderBook(symbol string) *OrderBook {
 	return &OrderBook{
 		Symbol: symbol,
+		bbo:    BBO{Symbol: symbol},
 		buys:   make(map[float64]*PriceLevel),
 		sells:  make(map[float64]*PriceLevel),
+		orders: make(map[string]*Order),
+		clock:  systemClock{},
+		ids:    uuidGenerator{},
 	}
 }
 
+func (orderbook *OrderBook) UseClock(clock Clock) {
+	orderbook.clock = clock
+}
+
+func (orderbook *OrderBook) UseIDGenerator(ids IDGenerator) {
+	orderbook.ids = ids
+}
+
 func (engine *Engine) GetBook(symbol string) *OrderBook {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
 
+	return engine.book(symbol)
+}
+
+func (engine *Engine) book(symbol string) *OrderBook {
 	book, ok := engine.books[symbol]
 	if !ok {
 		book = NewOrderBook(symbol)
+		book.UseClock(engine.clock)
+		book.UseIDGenerator(engine.ids)
+		book.onUpdate = engine.publishBookUpdate
 		engine.books[symbol] = book
 	}
 
 	return book
 }
 
+// FindOrder returns a copy of the resting order with the given ID from
+// whichever book holds it.
+func (engine *Engine) FindOrder(orderID string) (Order, bool) {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+
+	for _, book := range engine.books {
+		if order, ok := book.orders[orderID]; ok {
+			return *order, true
+		}
+	}
+
+	return Order{}, false
+}
+
+// Symbols lists the symbols that have a book, sorted.
+func (engine *Engine) Symbols() []string {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+
+	symbols := make([]string, 0, len(engine.books))
+	for symbol := range engine.books {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	return symbols
+}
+
+// BestPrices returns the best bid and ask of a symbol, zero for an empty side.
+func (engine *Engine) BestPrices(symbol string) (bid float64, ask float64) {
+	bbo, _ := engine.BBO(symbol)
+	return bbo.BidPrice, bbo.AskPrice
+}
+
+// OpenOrders returns copies of every resting order across all books.
+func (engine *Engine) OpenOrders() []Order {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+
+	var orders []Order
+	for _, book := range engine.books {
+		for _, order := range book.orders {
+			orders = append(orders, *order)
+		}
+	}
+
+	return orders
+}
+
 func (engine *Engine) GetBooks() map[string]*OrderBook {
 	if len(engine.books) == 0 {
 		return make(map[string]*OrderBook)
@@ -54,20 +128,29 @@ func (orderbook *OrderBook) MatchIncoming(order *Order) []*Trade {
 				maker := priceLevel.Peek()
 				execQuantity := math.Min(remaining, maker.Remaining)
 				trade := &Trade{
-					ID:          uuid.New().String(),
-					Symbol:      order.Symbol,
-					BuyOrderID:  order.ID,
-					SellOrderID: maker.ID,
-					Price:       maker.Price,
-					Quantity:    execQuantity,
-					ExecutedAt:  time.Now().UTC(),
+					ID:           orderbook.ids.NewID(),
+					Symbol:       order.Symbol,
+					BuyOrderID:   order.ID,
+					SellOrderID:  maker.ID,
+					MakerOrderID: maker.ID,
+					TakerOrderID: order.ID,
+					TakerSide:    Buy,
+					Price:        maker.Price,
+					Quantity:     execQuantity,
+					ExecutedAt:   orderbook.clock.Now(),
 				}
 
 				trades = append(trades, trade)
 				maker.Remaining -= execQuantity
+				priceLevel.Volume -= execQuantity
+				maker.Status = maker.fillStatus()
 				remaining -= execQuantity
 				if maker.Remaining <= 0 {
 					priceLevel.Dequeue()
+					delete(orderbook.orders, maker.ID)
+					orderbook.emit(BookOrderRemoved, maker)
+				} else {
+					orderbook.emit(BookOrderChanged, maker)
 				}
 			}
 
@@ -87,20 +170,29 @@ func (orderbook *OrderBook) MatchIncoming(order *Order) []*Trade {
 				maker := priceLevel.Peek()
 				execQuantity := math.Min(remaining, maker.Remaining)
 				trade := &Trade{
-					ID:          uuid.New().String(),
-					Symbol:      order.Symbol,
-					BuyOrderID:  maker.ID,
-					SellOrderID: order.ID,
-					Price:       maker.Price,
-					Quantity:    execQuantity,
-					ExecutedAt:  time.Now().UTC(),
+					ID:           orderbook.ids.NewID(),
+					Symbol:       order.Symbol,
+					BuyOrderID:   maker.ID,
+					SellOrderID:  order.ID,
+					MakerOrderID: maker.ID,
+					TakerOrderID: order.ID,
+					TakerSide:    Sell,
+					Price:        maker.Price,
+					Quantity:     execQuantity,
+					ExecutedAt:   orderbook.clock.Now(),
 				}
 
 				trades = append(trades, trade)
 				maker.Remaining -= execQuantity
+				priceLevel.Volume -= execQuantity
+				maker.Status = maker.fillStatus()
 				remaining -= execQuantity
 				if maker.Remaining <= 0 {
 					priceLevel.Dequeue()
+					delete(orderbook.orders, maker.ID)
+					orderbook.emit(BookOrderRemoved, maker)
+				} else {
+					orderbook.emit(BookOrderChanged, maker)
 				}
 			}
 
@@ -115,6 +207,11 @@ func (orderbook *OrderBook) MatchIncoming(order *Order) []*Trade {
 	}
 
 	order.Remaining = remaining
+	order.Status = order.fillStatus()
+	if order.Remaining > 0 && order.Price == 0 {
+		order.Status = OrderExpired
+	}
+
 	if order.Price > 0 && order.Remaining > 0 {
 		if order.Side == Buy {
 			orderbook.addPriceIfMissing(orderbook.buys, order.Price, true)
@@ -123,11 +220,97 @@ func (orderbook *OrderBook) MatchIncoming(order *Order) []*Trade {
 			orderbook.addPriceIfMissing(orderbook.sells, order.Price, false)
 			orderbook.sells[order.Price].Enqueue(order)
 		}
+		orderbook.orders[order.ID] = order
+		orderbook.emit(BookOrderOpened, order)
 	}
+	orderbook.refreshBBO()
 
 	return trades
 }
 
+func (orderbook *OrderBook) Cancel(orderID string) *Order {
+	order, ok := orderbook.orders[orderID]
+	if !ok {
+		return nil
+	}
+
+	delete(orderbook.orders, orderID)
+	order.Status = OrderCancelled
+	if order.Side == Buy {
+		orderbook.buys[order.Price].Remove(orderID)
+		orderbook.RemovePriceIfEmpty(orderbook.buys, order.Price, true)
+	} else {
+		orderbook.sells[order.Price].Remove(orderID)
+		orderbook.RemovePriceIfEmpty(orderbook.sells, order.Price, false)
+	}
+	orderbook.emit(BookOrderRemoved, order)
+	orderbook.refreshBBO()
+
+	return order
+}
+
+// Amend sets a new price and total quantity on a resting order. Shrinking the
+// quantity at an unchanged price is done in place; any other change takes the
+// order out of the book and matches it again as if it had just arrived.
+func (orderbook *OrderBook) Amend(orderID string, price float64, quantity float64) (*Order, []*Trade) {
+	order, ok := orderbook.orders[orderID]
+	if !ok {
+		return nil, nil
+	}
+
+	filled := order.Quantity - order.Remaining
+	remaining := quantity - filled
+	if remaining <= 0 {
+		orderbook.Cancel(orderID)
+		order.Quantity = filled
+		order.Remaining = 0
+		if filled > 0 {
+			order.Status = OrderFilled
+		}
+		return order, nil
+	}
+
+	if price == order.Price && quantity <= order.Quantity {
+		levels := orderbook.sells
+		if order.Side == Buy {
+			levels = orderbook.buys
+		}
+		levels[order.Price].Volume -= order.Remaining - remaining
+		order.Quantity = quantity
+		order.Remaining = remaining
+		order.Status = order.fillStatus()
+		orderbook.emit(BookOrderChanged, order)
+		orderbook.refreshBBO()
+		return order, nil
+	}
+
+	orderbook.Cancel(orderID)
+	order.Price = price
+	order.Quantity = quantity
+	order.Remaining = remaining
+
+	return order, orderbook.MatchIncoming(order)
+}
+
+// RestingOrders lists the orders in the book, best price first and in queue
+// order within a price.
+func (orderbook *OrderBook) RestingOrders() []*Order {
+	orders := make([]*Order, 0, len(orderbook.orders))
+	for _, price := range orderbook.buysPrices {
+		orders = append(orders, orderbook.buys[price].Orders...)
+	}
+	for _, price := range orderbook.sellsPrices {
+		orders = append(orders, orderbook.sells[price].Orders...)
+	}
+
+	return orders
+}
+
+func (orderbook *OrderBook) Order(orderID string) (*Order, bool) {
+	order, ok := orderbook.orders[orderID]
+	return order, ok
+}
+
 func (orderBook *OrderBook) RemovePriceIfEmpty(priceLevels map[float64]*PriceLevel, price float64, isBuy bool) {
 	priceLevel := priceLevels[price]
 	if priceLevel != nil && len(priceLevel.Orders) == 0 {
@@ -168,34 +351,8 @@ func (orderBook *OrderBook) addPriceIfMissing(priceLevels map[float64]*PriceLeve
 	}
 }
 
-func (orderbook *OrderBook) Snapshot(depth int) (bids []map[string]any, asks []map[string]any) {
-	for i, priceLevel := range orderbook.buysPrices {
-		if i >= depth {
-			break
-		}
-
-		volume := 0.0
-		for _, order := range orderbook.buys[priceLevel].Orders {
-			volume += order.Remaining
-		}
-
-		bids = append(bids, map[string]any{"price": priceLevel, "qty": volume})
-	}
-
-	for i, price := range orderbook.sellsPrices {
-		if i >= depth {
-			break
-		}
-
-		volume := 0.0
-		for _, order := range orderbook.sells[price].Orders {
-			volume += order.Remaining
-		}
-
-		asks = append(asks, map[string]any{"price": price, "qty": volume})
-	}
-
-	return
+func (orderbook *OrderBook) Snapshot(depth int) (bids []DepthLevel, asks []DepthLevel) {
+	return orderbook.Depth(DepthOptions{Depth: depth})
 }
 
 func (ob *OrderBook) AddOrder(order *Order) {
@@ -224,7 +381,11 @@ func (ob *OrderBook) AddOrder(order *Order) {
 		}
 	}
 
-	level.Orders = append(level.Orders, order)
+	order.Status = order.fillStatus()
+	level.Enqueue(order)
+	ob.orders[order.ID] = order
+	ob.emit(BookOrderOpened, order)
+	ob.refreshBBO()
 }
 
 func SortOrderbooks(orderbooks map[string]*OrderBook) {
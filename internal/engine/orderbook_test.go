@@ -2,6 +2,7 @@ package engine
 
 import (
 	"testing"
+	"time"
 )
 
 func TestMatchIncoming_LimitBuyOrderAddedToBookWhenUnfilled(t *testing.T) {
@@ -162,3 +163,129 @@ func TestMatchIncoming_SellMatchesBuys(t *testing.T) {
 		t.Errorf("expected incoming remaining 0, got %v", in.Remaining)
 	}
 }
+
+func TestCancel_RemovesOrderAndEmptyLevel(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+	ob.MatchIncoming(&Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 99, Quantity: 1, Remaining: 1})
+
+	cancelled := ob.Cancel("b1")
+	if cancelled == nil || cancelled.ID != "b1" {
+		t.Fatalf("expected b1 to be cancelled, got %v", cancelled)
+	}
+	if _, exists := ob.buys[100]; exists {
+		t.Errorf("expected price level 100 to be removed")
+	}
+	if len(ob.buysPrices) != 1 || ob.buysPrices[0] != 99 {
+		t.Errorf("expected only price 99 left, got %v", ob.buysPrices)
+	}
+	if ob.Cancel("b1") != nil {
+		t.Errorf("expected second cancel of b1 to be a no-op")
+	}
+}
+
+func TestAmend_QuantityDownKeepsPriority(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 3, Remaining: 3})
+	ob.MatchIncoming(&Order{ID: "s2", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 1, Remaining: 1})
+
+	order, trades := ob.Amend("s1", 100, 2)
+	if order == nil || len(trades) != 0 {
+		t.Fatalf("expected in-place amend without trades")
+	}
+	if ob.sells[100].Orders[0].ID != "s1" {
+		t.Errorf("expected s1 to keep its place at the front of the queue")
+	}
+	if order.Remaining != 2 {
+		t.Errorf("expected s1 remaining 2, got %v", order.Remaining)
+	}
+}
+
+func TestAmend_PriceChangeRematches(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 101, Quantity: 1, Remaining: 1})
+	ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 2, Remaining: 2})
+
+	order, trades := ob.Amend("b1", 101, 2)
+	if len(trades) != 1 || trades[0].Quantity != 1 {
+		t.Fatalf("expected one trade of 1 after crossing amend, got %v", trades)
+	}
+	if order.Remaining != 1 || order.Price != 101 {
+		t.Errorf("expected b1 resting 1 at 101, got %v at %v", order.Remaining, order.Price)
+	}
+	if _, exists := ob.buys[100]; exists {
+		t.Errorf("expected old price level 100 to be removed")
+	}
+}
+
+func TestMatchIncoming_UsesInjectedClockAndIDs(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	ob := NewOrderBook("SYM")
+	ob.UseClock(clock)
+	ob.UseIDGenerator(NewSequentialIDs("T"))
+
+	ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 2, Remaining: 2})
+	first := ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+	clock.Advance(time.Second)
+	second := ob.MatchIncoming(&Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+
+	if first[0].ID != "T1" || second[0].ID != "T2" {
+		t.Errorf("expected trade IDs T1 and T2, got %s and %s", first[0].ID, second[0].ID)
+	}
+	if !first[0].ExecutedAt.Equal(start) || !second[0].ExecutedAt.Equal(start.Add(time.Second)) {
+		t.Errorf("expected trades stamped by the manual clock, got %v and %v", first[0].ExecutedAt, second[0].ExecutedAt)
+	}
+}
+
+func TestSubmit_StampsIDAndCreatedAt(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := NewEngine(nil)
+	e.UseClock(NewManualClock(start))
+	e.UseIDGenerator(NewSequentialIDs("O"))
+
+	order := &Order{Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1}
+	if err := e.Submit(order); err != nil {
+		t.Fatal(err)
+	}
+
+	if order.ID != "O1" {
+		t.Errorf("expected order ID O1, got %s", order.ID)
+	}
+	if !order.CreatedAt.Equal(start) {
+		t.Errorf("expected CreatedAt %v, got %v", start, order.CreatedAt)
+	}
+}
+
+func TestMatchIncoming_SetsOrderStatus(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	maker := &Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 2, Remaining: 2}
+	ob.MatchIncoming(maker)
+	if maker.Status != OrderOpen {
+		t.Errorf("expected resting maker to be open, got %s", maker.Status)
+	}
+
+	taker := &Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1}
+	ob.MatchIncoming(taker)
+	if taker.Status != OrderFilled {
+		t.Errorf("expected taker to be filled, got %s", taker.Status)
+	}
+	if maker.Status != OrderPartiallyFilled {
+		t.Errorf("expected maker to be partially filled, got %s", maker.Status)
+	}
+
+	market := &Order{ID: "b2", Symbol: "SYM", Side: Buy, Price: 0, Quantity: 3, Remaining: 3}
+	ob.MatchIncoming(market)
+	if market.Status != OrderExpired || market.Remaining != 2 {
+		t.Errorf("expected market remainder of 2 to expire, got %s with %v", market.Status, market.Remaining)
+	}
+	if maker.Status != OrderFilled {
+		t.Errorf("expected maker to be filled, got %s", maker.Status)
+	}
+
+	ob.MatchIncoming(&Order{ID: "s2", Symbol: "SYM", Side: Sell, Price: 101, Quantity: 1, Remaining: 1})
+	if cancelled := ob.Cancel("s2"); cancelled.Status != OrderCancelled {
+		t.Errorf("expected cancelled status, got %s", cancelled.Status)
+	}
+}
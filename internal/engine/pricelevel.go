@@ -1,7 +1,11 @@
 package engine
 
+// PriceLevel is the queue of orders resting at one price. Volume is the sum
+// of their remaining quantities and is kept in step by every method here;
+// code that fills an order in place must reduce it as well.
 type PriceLevel struct {
 	Price  float64
+	Volume float64
 	Orders []*Order
 }
 
@@ -18,10 +22,24 @@ func (priceLevel *PriceLevel) Dequeue() *Order {
 	}
 	order := priceLevel.Orders[0]
 	priceLevel.Orders = priceLevel.Orders[1:]
+	priceLevel.Volume -= order.Remaining
 
 	return order
 }
 
 func (priceLevel *PriceLevel) Enqueue(order *Order) {
 	priceLevel.Orders = append(priceLevel.Orders, order)
+	priceLevel.Volume += order.Remaining
+}
+
+func (priceLevel *PriceLevel) Remove(orderID string) *Order {
+	for i, order := range priceLevel.Orders {
+		if order.ID == orderID {
+			priceLevel.Orders = append(priceLevel.Orders[:i:i], priceLevel.Orders[i+1:]...)
+			priceLevel.Volume -= order.Remaining
+			return order
+		}
+	}
+
+	return nil
 }
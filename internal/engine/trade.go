@@ -1,13 +1,61 @@
 package engine
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Trade struct {
-    ID string `json:"id"` 
-    Symbol string `json:"symbol"`
-    BuyOrderID string `json:"buy_order_id"`
-    SellOrderID string `json:"sel_order_id"`
-    Price float64 `json:"price"`
-    Quantity float64 `json:"quantity"`
-    ExecutedAt time.Time `json:"executed_at"`
+	ID           string    `json:"id"`
+	Symbol       string    `json:"symbol"`
+	BuyOrderID   string    `json:"buy_order_id"`
+	SellOrderID  string    `json:"sell_order_id"`
+	MakerOrderID string    `json:"maker_order_id"`
+	TakerOrderID string    `json:"taker_order_id"`
+	TakerSide    Side      `json:"taker_side"`
+	Price        float64   `json:"price"`
+	Quantity     float64   `json:"quantity"`
+	ExecutedAt   time.Time `json:"executed_at"`
+}
+
+// UnmarshalJSON also accepts trades published before the sell order ID was
+// renamed from the misspelled "sel_order_id". Such trades carry no taker
+// information, so the maker and taker fields stay empty.
+func (trade *Trade) UnmarshalJSON(data []byte) error {
+	type plain Trade
+	var decoded struct {
+		plain
+		LegacySellOrderID string `json:"sel_order_id"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*trade = Trade(decoded.plain)
+	if trade.SellOrderID == "" {
+		trade.SellOrderID = decoded.LegacySellOrderID
+	}
+
+	return nil
+}
+
+// Trades returns a new feed of the trades each command executes. Unlike the
+// trade events, which consumers may read from any offset, it only carries
+// executions made by this process, as they happen. Every call returns its own
+// feed. Like BookUpdates it must be called before Start, and trades are
+// dropped rather than holding up matching if a reader falls behind.
+func (engine *Engine) Trades() <-chan []*Trade {
+	feed := make(chan []*Trade, bookUpdateBuffer)
+	engine.tradeFeeds = append(engine.tradeFeeds, feed)
+
+	return feed
+}
+
+func (engine *Engine) publishTrades(trades []*Trade) {
+	for _, feed := range engine.tradeFeeds {
+		select {
+		case feed <- trades:
+		default:
+		}
+	}
 }
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchIncoming_RecordsTakerAndMaker(t *testing.T) {
+	ob := NewOrderBook("SYM")
+	ob.MatchIncoming(&Order{ID: "b1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1})
+
+	trades := ob.MatchIncoming(&Order{ID: "s1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 1, Remaining: 1})
+	if len(trades) != 1 {
+		t.Fatalf("expected 1 trade, got %d", len(trades))
+	}
+
+	trade := trades[0]
+	if trade.TakerSide != Sell || trade.TakerOrderID != "s1" || trade.MakerOrderID != "b1" {
+		t.Errorf("expected sell taker s1 against maker b1, got %s taker %s maker %s", trade.TakerSide, trade.TakerOrderID, trade.MakerOrderID)
+	}
+}
+
+func TestTradeUnmarshal_AcceptsLegacySellOrderID(t *testing.T) {
+	var trade Trade
+	if err := json.Unmarshal([]byte(`{"id":"t1","buy_order_id":"b1","sel_order_id":"s1","price":10}`), &trade); err != nil {
+		t.Fatal(err)
+	}
+	if trade.SellOrderID != "s1" || trade.Price != 10 {
+		t.Errorf("expected legacy sell order id s1 and price 10, got %q and %v", trade.SellOrderID, trade.Price)
+	}
+
+	encoded, _ := json.Marshal(&Trade{ID: "t2", SellOrderID: "s2"})
+	var decoded Trade
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SellOrderID != "s2" {
+		t.Errorf("expected sell order id s2 after round trip, got %q", decoded.SellOrderID)
+	}
+}
+
+func TestTrades_FeedsEveryReader(t *testing.T) {
+	engine := NewEngine(map[string]EventWriter{})
+	first, second := engine.Trades(), engine.Trades()
+
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "m1", Symbol: "SYM", Side: Sell, Price: 100, Quantity: 1, Remaining: 1}})
+	engine.process(&Command{Type: NewOrderCommand, Order: &Order{ID: "t1", Symbol: "SYM", Side: Buy, Price: 100, Quantity: 1, Remaining: 1}})
+
+	for _, feed := range []<-chan []*Trade{first, second} {
+		select {
+		case trades := <-feed:
+			if len(trades) != 1 || trades[0].MakerOrderID != "m1" || trades[0].TakerOrderID != "t1" {
+				t.Fatalf("expected the m1/t1 trade, got %+v", trades)
+			}
+		default:
+			t.Fatalf("expected every feed to get the trade")
+		}
+		if len(feed) != 0 {
+			t.Fatalf("expected only commands that trade on the feed")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Event is the envelope every publisher writes and every consumer reads,
+// regardless of the transport in between.
+type Event struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type EventHandler func(ctx context.Context, event Event)
+
+func OrderPersistenceHandler(db *pgxpool.Pool) EventHandler {
+	return func(ctx context.Context, event Event) {
+		var order *engine.Order
+		if err := json.Unmarshal(event.Payload, &order); err != nil {
+			log.Fatal("unmarshal order err:", err)
+			return
+		}
+
+		switch event.Type {
+		case "order_added":
+			persistOrder(ctx, db, order)
+		case "order_cancelled", "order_filled", "order_expired":
+			persistOrderStatus(ctx, db, order)
+		case "order_amended":
+			persistOrderAmend(ctx, db, order)
+		default:
+			log.Fatal("The event is in the wrong topic")
+		}
+	}
+}
+
+func TradePersistenceHandler(db *pgxpool.Pool) EventHandler {
+	return func(ctx context.Context, event Event) {
+		if event.Type != "order_matched" {
+			log.Fatal("The event is in the wrong topic")
+			return
+		}
+
+		var trades []*engine.Trade
+		if err := json.Unmarshal(event.Payload, &trades); err != nil {
+			log.Println("unmarshal trade err:", err)
+			return
+		}
+
+		persistTrades(ctx, db, trades)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/cemsubasi/orderbook/internal/engine"
@@ -13,7 +14,7 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
-func StartKafkaOrderConsumer(brokers []string, db *pgxpool.Pool, ctx context.Context) {
+func StartKafkaConsumer(brokers []string, topic string, groupID string, handler EventHandler, ctx context.Context) {
 	go func() {
 		for {
 			select {
@@ -22,10 +23,11 @@ func StartKafkaOrderConsumer(brokers []string, db *pgxpool.Pool, ctx context.Con
 			default:
 				reader := kafka.NewReader(kafka.ReaderConfig{
 					Brokers: brokers,
-					Topic:   engine.OrderTopic,
-					GroupID: "order_handler",
+					Topic:   topic,
+					GroupID: groupID,
 				})
-				log.Println("Kafka order consumer connected")
+				log.Printf("Kafka %s consumer connected", groupID)
+
 				for {
 					select {
 					case <-ctx.Done():
@@ -47,27 +49,13 @@ func StartKafkaOrderConsumer(brokers []string, db *pgxpool.Pool, ctx context.Con
 							break
 						}
 
-						var event struct {
-							Type    string          `json:"type"`
-							Payload json.RawMessage `json:"payload"`
-						}
+						var event Event
 						if err := json.Unmarshal(m.Value, &event); err != nil {
-							log.Fatal("unmarshal event err:", err)
-							continue
-						}
-
-						if event.Type != "order_added" {
-							log.Fatal("The kafka message is in the wrong topic")
-							return
-						}
-
-						var order *engine.Order
-						if err := json.Unmarshal(event.Payload, &order); err != nil {
-							log.Fatal("unmarshal order err:", err)
+							log.Println("unmarshal event err:", err)
 							continue
 						}
 
-						persistOrder(ctx, db, order)
+						handler(ctx, event)
 					}
 				}
 			}
@@ -75,100 +63,97 @@ func StartKafkaOrderConsumer(brokers []string, db *pgxpool.Pool, ctx context.Con
 	}()
 }
 
-func StartKafkaTradeConsumer(brokers []string, db *pgxpool.Pool, ctx context.Context) {
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				log.Println("Creating reader...")
-				reader := kafka.NewReader(kafka.ReaderConfig{
-					Brokers: brokers,
-					Topic:   engine.TradeTopic,
-					GroupID: "trade_handler",
-				})
-				log.Println("Kafka trade consumer connected")
-
-				for {
-					select {
-					case <-ctx.Done():
-						log.Println("Kafka consumer stopping during read...")
-						_ = reader.Close()
-						return
-					default:
-						m, err := reader.ReadMessage(ctx)
-						if err != nil {
-							if errors.Is(err, context.Canceled) {
-								log.Println("Kafka consumer read canceled due to context shutdown")
-								return
-							}
-
-							log.Println("kafka read err:", err)
-							log.Println("Reconnecting to Kafka in 1 second...")
-							_ = reader.Close()
-							time.Sleep(1 * time.Second)
-							break
-						}
-
-						var event struct {
-							Type    string          `json:"type"`
-							Payload json.RawMessage `json:"payload"`
-						}
-						if err := json.Unmarshal(m.Value, &event); err != nil {
-							log.Println("unmarshal event err:", err)
-							continue
-						}
-
-						if event.Type != "order_matched" {
-							log.Fatal("The kafka message is in the wrong topic")
-							continue
-						}
+// Order events are published from separate goroutines, so a cancel or amend
+// can reach the consumer before the order_added it follows. Every write is
+// therefore an upsert: a late order_added leaves the row it finds alone.
+func persistOrder(ctx context.Context, db *pgxpool.Pool, order *engine.Order) {
+	_, err := db.Exec(ctx, `INSERT INTO orders (id, symbol, side, price, quantity, remaining, status, created_at, account)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO NOTHING`,
+		order.ID, order.Symbol, order.Side, order.Price, order.Quantity, order.Remaining, order.Status, orderCreatedAt(order), nullable(order.Account))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("Database operation canceled due to context shutdown")
+			return
+		}
 
-						var trades []*engine.Trade
-						if err := json.Unmarshal(event.Payload, &trades); err != nil {
-							log.Println("unmarshal trade err:", err)
-							continue
-						}
+		log.Fatal("persist order err:", err)
+	}
+}
 
-						persistTrades(ctx, db, trades)
-					}
-				}
-			}
+// Filled, cancelled and expired are final, so an update that arrives after
+// one of them is stale and is dropped. Rows written before statuses were
+// recorded have none and are still open.
+const notFinal = `WHERE orders.status IS NULL OR orders.status NOT IN ('filled', 'cancelled', 'expired')`
+
+// persistOrderStatus also records orders that never rested because they
+// filled or expired on arrival.
+func persistOrderStatus(ctx context.Context, db *pgxpool.Pool, order *engine.Order) {
+	_, err := db.Exec(ctx, `INSERT INTO orders (id, symbol, side, price, quantity, remaining, status, created_at, account)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET remaining = EXCLUDED.remaining, status = EXCLUDED.status
+		`+notFinal,
+		order.ID, order.Symbol, order.Side, order.Price, order.Quantity, order.Remaining, order.Status, orderCreatedAt(order), nullable(order.Account))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("Database operation canceled due to context shutdown")
+			return
 		}
-	}()
+
+		log.Fatal("persist order status err:", err)
+	}
 }
 
-func persistOrder(ctx context.Context, db *pgxpool.Pool, order *engine.Order) {
-	_, err := db.Exec(ctx, `INSERT INTO orders (id, symbol, side, price, quantity, remaining, created_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		order.ID, order.Symbol, order.Side, order.Price, order.Quantity, order.Remaining, time.Now().UTC())
+func persistOrderAmend(ctx context.Context, db *pgxpool.Pool, order *engine.Order) {
+	_, err := db.Exec(ctx, `INSERT INTO orders (id, symbol, side, price, quantity, remaining, status, created_at, account)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET price = EXCLUDED.price, quantity = EXCLUDED.quantity,
+			remaining = EXCLUDED.remaining, status = EXCLUDED.status
+		`+notFinal,
+		order.ID, order.Symbol, order.Side, order.Price, order.Quantity, order.Remaining, order.Status, orderCreatedAt(order), nullable(order.Account))
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			log.Println("Database operation canceled due to context shutdown")
 			return
 		}
 
-		log.Fatal("persist order err:", err)
-	} else {
-		// log.Printf("Order %s persisted/updated with remaining %f", order.ID, order.Remaining)
+		log.Fatal("persist order amend err:", err)
+	}
+}
+
+// orderCreatedAt falls back to the current time for orders published before
+// the engine stamped CreatedAt on acceptance.
+func orderCreatedAt(order *engine.Order) time.Time {
+	if order.CreatedAt.IsZero() {
+		return time.Now().UTC()
 	}
+
+	return order.CreatedAt
 }
 
+// persistTrades copies the trades into a temporary table and inserts them
+// from there, skipping IDs already stored. Both the Kafka and Postgres
+// consumers can deliver a batch again after a restart.
 func persistTrades(ctx context.Context, db *pgxpool.Pool, trades []*engine.Trade) {
-	columns := []string{"id", "symbol", "buy_order_id", "sell_order_id", "price", "quantity", "executed_at"}
+	columns := []string{"id", "symbol", "buy_order_id", "sell_order_id", "maker_order_id", "taker_order_id", "taker_side", "price", "quantity", "executed_at"}
 
 	rows := make([][]interface{}, len(trades))
 	for i, t := range trades {
-		rows[i] = []interface{}{t.ID, t.Symbol, t.BuyOrderID, t.SellOrderID, t.Price, t.Quantity, t.ExecutedAt}
+		rows[i] = []interface{}{t.ID, t.Symbol, t.BuyOrderID, t.SellOrderID, nullable(t.MakerOrderID), nullable(t.TakerOrderID), nullable(string(t.TakerSide)), t.Price, t.Quantity, t.ExecutedAt}
 	}
 
-	_, err := db.CopyFrom(
-		ctx,
-		pgx.Identifier{"trades"},
-		columns,
-		pgx.CopyFromRows(rows),
-	)
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `CREATE TEMP TABLE incoming_trades (LIKE trades) ON COMMIT DROP`); err != nil {
+			return err
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"incoming_trades"}, columns, pgx.CopyFromRows(rows)); err != nil {
+			return err
+		}
+
+		list := strings.Join(columns, ", ")
+		_, err := tx.Exec(ctx, `INSERT INTO trades (`+list+`) SELECT `+list+` FROM incoming_trades ON CONFLICT (id) DO NOTHING`)
+		return err
+	})
 
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
@@ -179,3 +164,13 @@ func persistTrades(ctx context.Context, db *pgxpool.Pool, trades []*engine.Trade
 		log.Fatal("persist trades err:", err)
 	}
 }
+
+// nullable stores empty strings as NULL, which is what trades published
+// before taker information existed carry.
+func nullable(value string) any {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
@@ -0,0 +1,129 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDB connects to the database in TEST_DATABASE_URL and migrates a fresh
+// schema that is dropped when the test ends. Tests that need it are skipped
+// without one.
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrations, _ := filepath.Glob("../db/migrations/*.up.sql")
+	sort.Strings(migrations)
+	for _, migration := range migrations {
+		sql, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("%s: %v", migration, err)
+		}
+	}
+
+	return pool
+}
+
+func orderStatus(t *testing.T, db *pgxpool.Pool, id string) (string, float64) {
+	t.Helper()
+
+	var status string
+	var price float64
+	if err := db.QueryRow(context.Background(), `SELECT COALESCE(status, ''), price FROM orders WHERE id = $1`, id).Scan(&status, &price); err != nil {
+		t.Fatal(err)
+	}
+
+	return status, price
+}
+
+func TestPersistOrderStatus_UpdatesRowsWithoutStatus(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	// rows written before statuses were recorded
+	for _, id := range []string{"o1", "o2"} {
+		if _, err := db.Exec(ctx, `INSERT INTO orders (id, symbol, side, price, quantity, remaining, created_at)
+			VALUES ($1, 'SYM', 'buy', 100, 1, 1, NOW())`, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order := &engine.Order{ID: "o1", Symbol: "SYM", Side: engine.Buy, Price: 100, Quantity: 1, Status: engine.OrderCancelled}
+	persistOrderStatus(ctx, db, order)
+	if status, _ := orderStatus(t, db, "o1"); status != string(engine.OrderCancelled) {
+		t.Errorf("expected o1 cancelled, got %q", status)
+	}
+
+	amended := &engine.Order{ID: "o2", Symbol: "SYM", Side: engine.Buy, Price: 99, Quantity: 1, Remaining: 1, Status: engine.OrderOpen}
+	persistOrderAmend(ctx, db, amended)
+	if _, price := orderStatus(t, db, "o2"); price != 99 {
+		t.Errorf("expected o2 amended to 99, got %v", price)
+	}
+
+	// a final status is never overwritten
+	amended.ID = "o1"
+	persistOrderAmend(ctx, db, amended)
+	if status, price := orderStatus(t, db, "o1"); status != string(engine.OrderCancelled) || price != 100 {
+		t.Errorf("expected o1 to stay cancelled at 100, got %q at %v", status, price)
+	}
+}
+
+func TestPersistTrades_SkipsRedeliveredTrades(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	first := []*engine.Trade{
+		{ID: "t1", Symbol: "SYM", BuyOrderID: "b1", SellOrderID: "s1", Price: 100, Quantity: 1, ExecutedAt: at},
+		{ID: "t2", Symbol: "SYM", BuyOrderID: "b1", SellOrderID: "s2", Price: 100, Quantity: 1, ExecutedAt: at},
+	}
+	persistTrades(ctx, db, first)
+	// a redelivered batch that overlaps the first
+	persistTrades(ctx, db, append(first[1:], &engine.Trade{ID: "t3", Symbol: "SYM", BuyOrderID: "b2", SellOrderID: "s3", Price: 101, Quantity: 2, ExecutedAt: at}))
+
+	var count int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM trades`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 trades, got %d", count)
+	}
+}
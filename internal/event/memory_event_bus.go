@@ -0,0 +1,97 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+const memorySubscriberBuffer = 100000
+
+// MemoryBus delivers events between goroutines of the same process. Each
+// subscriber behaves like its own Kafka consumer group and sees every event
+// published to its topic after it subscribed. Events published by one
+// goroutine arrive in that order, but the engine publishes every event from
+// a goroutine of its own, so consumers can't rely on the order of events
+// published close together.
+type MemoryBus struct {
+	subscribers map[string][]memorySubscriber
+	mu          sync.RWMutex
+}
+
+type memorySubscriber struct {
+	events chan Event
+	done   <-chan struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subscribers: make(map[string][]memorySubscriber)}
+}
+
+// Subscribe returns the events published to topic until ctx is cancelled,
+// after which publishers no longer wait for the subscriber.
+func (bus *MemoryBus) Subscribe(topic string, ctx context.Context) <-chan Event {
+	subscriber := memorySubscriber{events: make(chan Event, memorySubscriberBuffer), done: ctx.Done()}
+
+	bus.mu.Lock()
+	bus.subscribers[topic] = append(bus.subscribers[topic], subscriber)
+	bus.mu.Unlock()
+
+	return subscriber.events
+}
+
+// publish encodes the payload up front, as Kafka would, so consumers never
+// share memory with the engine's live orders. A subscriber whose buffer is
+// full holds up the publisher until it catches up or is cancelled, without
+// holding the lock that Subscribe needs.
+func (bus *MemoryBus) publish(topic string, eventType string, payload any) error {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	event := Event{Type: eventType, Payload: bytes}
+
+	bus.mu.RLock()
+	subscribers := bus.subscribers[topic]
+	bus.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		select {
+		case subscriber.events <- event:
+		case <-subscriber.done:
+		}
+	}
+
+	return nil
+}
+
+type MemoryPublisher struct {
+	bus   *MemoryBus
+	topic string
+}
+
+func NewMemoryPublisher(bus *MemoryBus, topic string) *MemoryPublisher {
+	return &MemoryPublisher{bus: bus, topic: topic}
+}
+
+func (p *MemoryPublisher) Publish(eventType string, payload any) error {
+	return p.bus.publish(p.topic, eventType, payload)
+}
+
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
+
+func StartMemoryConsumer(bus *MemoryBus, topic string, handler EventHandler, ctx context.Context) {
+	events := bus.Subscribe(topic, ctx)
+	go func() {
+		for {
+			select {
+			case event := <-events:
+				handler(ctx, event)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMemoryBus_DeliversToEverySubscriberInOrder(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan Event, 10)
+	StartMemoryConsumer(bus, "topic", func(ctx context.Context, event Event) { received <- event }, ctx)
+	other := bus.Subscribe("topic", ctx)
+
+	publisher := NewMemoryPublisher(bus, "topic")
+	for i := 1; i <= 3; i++ {
+		if err := publisher.Publish("counted", map[string]int{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	NewMemoryPublisher(bus, "elsewhere").Publish("ignored", nil)
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case event := <-received:
+			var payload map[string]int
+			json.Unmarshal(event.Payload, &payload)
+			if event.Type != "counted" || payload["n"] != i {
+				t.Errorf("expected counted event %d, got %s %v", i, event.Type, payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+
+	if len(other) != 3 {
+		t.Errorf("expected second subscriber to get 3 events, got %d", len(other))
+	}
+}
+
+func TestMemoryBus_SkipsCancelledSubscribers(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := bus.Subscribe("topic", ctx)
+	cancel()
+
+	// fill the cancelled subscriber's buffer so that only its cancellation
+	// can let the next publish through
+	publisher := NewMemoryPublisher(bus, "topic")
+	for len(stopped) < cap(stopped) {
+		publisher.Publish("filler", nil)
+	}
+
+	published := make(chan error, 1)
+	go func() { published <- publisher.Publish("late", nil) }()
+
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a cancelled subscriber")
+	}
+
+	live := bus.Subscribe("topic", context.Background())
+	publisher.Publish("after", nil)
+	if len(live) != 1 {
+		t.Errorf("expected a new subscriber to get the next event, got %d", len(live))
+	}
+}
@@ -0,0 +1,112 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	postgresBatchSize    = 500
+	postgresPollInterval = 5 * time.Second
+)
+
+// StartPostgresConsumer tails the events table for topic on behalf of a
+// consumer group. Events are handled in id order and the group's cursor is
+// stored after each batch, so like the Kafka consumers a restart resumes
+// where the group left off and may see the last batch again. Notifications
+// only wake the consumer up; the cursor is what decides what is read.
+func StartPostgresConsumer(db *pgxpool.Pool, topic string, groupID string, handler EventHandler, ctx context.Context) {
+	go func() {
+		for {
+			err := consumePostgresEvents(db, topic, groupID, handler, ctx)
+			if ctx.Err() != nil {
+				log.Println("Postgres consumer stopping...")
+				return
+			}
+
+			log.Println("postgres consume err:", err)
+			log.Println("Reconnecting to Postgres in 1 second...")
+			time.Sleep(1 * time.Second)
+		}
+	}()
+}
+
+func consumePostgresEvents(db *pgxpool.Pool, topic string, groupID string, handler EventHandler, ctx context.Context) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresEventChannel); err != nil {
+		return err
+	}
+	log.Printf("Postgres %s consumer connected", groupID)
+
+	var cursor int64
+	err = db.QueryRow(ctx, `SELECT last_id FROM event_offsets WHERE group_id = $1 AND topic = $2`, groupID, topic).Scan(&cursor)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	for {
+		read, err := readPostgresEvents(db, topic, cursor, ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range read {
+			handler(ctx, row.event)
+			cursor = row.id
+		}
+
+		if len(read) > 0 {
+			_, err := db.Exec(ctx, `INSERT INTO event_offsets (group_id, topic, last_id) VALUES ($1, $2, $3)
+				ON CONFLICT (group_id, topic) DO UPDATE SET last_id = EXCLUDED.last_id`, groupID, topic, cursor)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(read) == postgresBatchSize {
+			continue
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, postgresPollInterval)
+		_, err = conn.Conn().WaitForNotification(waitCtx)
+		cancel()
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+	}
+}
+
+type postgresEvent struct {
+	id    int64
+	event Event
+}
+
+func readPostgresEvents(db *pgxpool.Pool, topic string, cursor int64, ctx context.Context) ([]postgresEvent, error) {
+	rows, err := db.Query(ctx, `SELECT id, type, payload FROM events WHERE topic = $1 AND id > $2 ORDER BY id LIMIT $3`,
+		topic, cursor, postgresBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []postgresEvent
+	for rows.Next() {
+		var row postgresEvent
+		if err := rows.Scan(&row.id, &row.event.Type, &row.event.Payload); err != nil {
+			return nil, err
+		}
+		events = append(events, row)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,64 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postgresEventChannel = "orderbook_events"
+
+type PostgresPublisher struct {
+	db    *pgxpool.Pool
+	topic string
+}
+
+func NewPostgresPublisher(db *pgxpool.Pool, topic string) *PostgresPublisher {
+	return &PostgresPublisher{db: db, topic: topic}
+}
+
+// Publish appends the event to the events table and notifies listeners once
+// the row is committed. Inserts into a topic are serialized with an advisory
+// lock so ids become visible in increasing order and a consumer's cursor can
+// never step over a row that commits late.
+func (p *PostgresPublisher) Publish(eventType string, payload any) error {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		log.Printf("postgres publish err: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, p.topic); err != nil {
+		log.Printf("postgres publish err: %v", err)
+		return err
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx, `INSERT INTO events (topic, type, payload) VALUES ($1, $2, $3) RETURNING id`,
+		p.topic, eventType, json.RawMessage(bytes)).Scan(&id)
+	if err != nil {
+		log.Printf("postgres publish err: %v", err)
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, postgresEventChannel, p.topic+":"+strconv.FormatInt(id, 10)); err != nil {
+		log.Printf("postgres publish err: %v", err)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,182 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// Every record is laid out as a big-endian uint32 payload length, a
+// big-endian uint32 CRC-32C of the payload, and the JSON encoded command.
+const (
+	headerSize    = 8
+	maxRecordSize = 16 << 20
+	segmentPrefix = "journal-"
+	segmentSuffix = ".log"
+)
+
+const DefaultSegmentSize int64 = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type Journal struct {
+	dir            string
+	maxSegmentSize int64
+	file           *os.File
+	size           int64
+	mu             sync.Mutex
+}
+
+// Open prepares dir for appending. A torn record at the end of the newest
+// segment, left behind by a crash in the middle of a write, is cut off so new
+// records start on a clean boundary.
+func Open(dir string, maxSegmentSize int64) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create journal dir err: %w", err)
+	}
+
+	journal := &Journal{dir: dir, maxSegmentSize: maxSegmentSize}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return journal, nil
+	}
+
+	last := segments[len(segments)-1]
+	valid, err := scanSegment(last, nil)
+	if err != nil && err != errTornRecord {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(last, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal segment err: %w", err)
+	}
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("truncate journal segment err: %w", err)
+	}
+
+	journal.file = file
+	journal.size = valid
+
+	return journal, nil
+}
+
+func (journal *Journal) Write(commands []*engine.Command) error {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	header := make([]byte, headerSize)
+	for _, command := range commands {
+		payload, err := json.Marshal(command)
+		if err != nil {
+			return fmt.Errorf("marshal command err: %w", err)
+		}
+
+		binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, crcTable))
+		buf.Write(header)
+		buf.Write(payload)
+	}
+
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if journal.file == nil || journal.size >= journal.maxSegmentSize {
+		if err := journal.rotate(commands[0].Seq); err != nil {
+			return err
+		}
+	}
+
+	offset := journal.size
+	n, err := journal.file.Write(buf.Bytes())
+	if err == nil {
+		err = journal.file.Sync()
+	}
+	if err != nil {
+		_ = journal.file.Truncate(offset)
+		return fmt.Errorf("write journal err: %w", err)
+	}
+
+	journal.size += int64(n)
+
+	return nil
+}
+
+func (journal *Journal) Close() error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if journal.file == nil {
+		return nil
+	}
+
+	err := journal.file.Close()
+	journal.file = nil
+
+	return err
+}
+
+// rotate starts a new segment named after the first sequence number it holds,
+// so lexical order of the file names is also replay order.
+func (journal *Journal) rotate(firstSeq uint64) error {
+	if journal.file != nil {
+		if err := journal.file.Close(); err != nil {
+			return fmt.Errorf("close journal segment err: %w", err)
+		}
+		journal.file = nil
+	}
+
+	name := filepath.Join(journal.dir, fmt.Sprintf("%s%020d%s", segmentPrefix, firstSeq, segmentSuffix))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("create journal segment err: %w", err)
+	}
+
+	if dir, err := os.Open(journal.dir); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	journal.file = file
+	journal.size = 0
+
+	return nil
+}
+
+func listSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read journal dir err: %w", err)
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		segments = append(segments, filepath.Join(dir, name))
+	}
+	sort.Strings(segments)
+
+	return segments, nil
+}
@@ -0,0 +1,112 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+func newOrderCommand(seq uint64, id string, side engine.Side, price float64, qty float64) *engine.Command {
+	return &engine.Command{
+		Seq:   seq,
+		Type:  engine.NewOrderCommand,
+		Order: &engine.Order{ID: id, Symbol: "SYM", Side: side, Price: price, Quantity: qty, Remaining: qty},
+	}
+}
+
+func TestRebuild_ReplaysAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a segment size of one byte forces a rotation on every write
+	batches := [][]*engine.Command{
+		{newOrderCommand(1, "s1", engine.Sell, 100, 2)},
+		{newOrderCommand(2, "b1", engine.Buy, 100, 1)},
+		{{Seq: 3, Type: engine.AmendOrderCommand, Symbol: "SYM", OrderID: "s1", Price: 101, Quantity: 3}},
+	}
+	for _, batch := range batches {
+		if err := j.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	segments, _ := listSegments(dir)
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments, got %d", len(segments))
+	}
+
+	books, err := Rebuild(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, ok := books["SYM"].Order("s1")
+	if !ok {
+		t.Fatalf("expected s1 to be resting")
+	}
+	if order.Price != 101 || order.Remaining != 2 {
+		t.Errorf("expected s1 resting 2 at 101, got %v at %v", order.Remaining, order.Price)
+	}
+}
+
+func TestOpen_TruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, DefaultSegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Write([]*engine.Command{newOrderCommand(1, "b1", engine.Buy, 100, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	segments, _ := listSegments(dir)
+	f, _ := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0o644)
+	f.Write([]byte{0, 0, 0, 42, 1, 2})
+	f.Close()
+
+	count, err := Replay(dir, func(*engine.Command) error { return nil })
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 command and no error, got %d, %v", count, err)
+	}
+
+	j, err = Open(dir, DefaultSegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Write([]*engine.Command{newOrderCommand(2, "b2", engine.Buy, 99, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	count, err = Replay(dir, func(*engine.Command) error { return nil })
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 commands after reopening, got %d, %v", count, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "journal-00000000000000000001.log")); err != nil {
+		t.Errorf("expected first segment to be named after seq 1: %v", err)
+	}
+}
+
+func TestReplay_RejectsCorruptionBeforeLastSegment(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := Open(dir, 1)
+	j.Write([]*engine.Command{newOrderCommand(1, "b1", engine.Buy, 100, 1)})
+	j.Write([]*engine.Command{newOrderCommand(2, "b2", engine.Buy, 100, 1)})
+	j.Close()
+
+	segments, _ := listSegments(dir)
+	data, _ := os.ReadFile(segments[0])
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(segments[0], data, 0o644)
+
+	if _, err := Replay(dir, func(*engine.Command) error { return nil }); err == nil {
+		t.Fatalf("expected corruption in an older segment to fail replay")
+	}
+}
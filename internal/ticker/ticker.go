@@ -0,0 +1,221 @@
+package ticker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// The 24 hour window rolls forward one minute at a time: trades are summed
+// into per-minute buckets and a ticker covers the buckets of the last 1440
+// minutes, the current one included.
+const (
+	bucketSize = time.Minute
+	windowSize = 24 * time.Hour
+)
+
+type Ticker struct {
+	Symbol             string    `json:"symbol"`
+	LastPrice          float64   `json:"last_price"`
+	LastQuantity       float64   `json:"last_quantity"`
+	OpenPrice          float64   `json:"open_price"`
+	HighPrice          float64   `json:"high_price"`
+	LowPrice           float64   `json:"low_price"`
+	Volume             float64   `json:"volume"`
+	QuoteVolume        float64   `json:"quote_volume"`
+	PriceChange        float64   `json:"price_change"`
+	PriceChangePercent float64   `json:"price_change_percent"`
+	Trades             int64     `json:"trades"`
+	BestBid            float64   `json:"best_bid"`
+	BestAsk            float64   `json:"best_ask"`
+	OpenTime           time.Time `json:"open_time"`
+	CloseTime          time.Time `json:"close_time"`
+}
+
+type bucket struct {
+	start       time.Time
+	firstAt     time.Time
+	lastAt      time.Time
+	open        float64
+	high        float64
+	low         float64
+	close       float64
+	closeQty    float64
+	volume      float64
+	quoteVolume float64
+	trades      int64
+}
+
+type BookSource interface {
+	BestPrices(symbol string) (bid float64, ask float64)
+	Symbols() []string
+}
+
+type Tracker struct {
+	books   BookSource
+	clock   engine.Clock
+	buckets map[string][]*bucket
+	mu      sync.RWMutex
+}
+
+func NewTracker(books BookSource, clock engine.Clock) *Tracker {
+	return &Tracker{
+		books:   books,
+		clock:   clock,
+		buckets: make(map[string][]*bucket),
+	}
+}
+
+// Follow applies the engine's live trades until ctx is done. Trades from
+// before the process started are not on the feed; load the ones since
+// WindowStart with Apply.
+func (tracker *Tracker) Follow(trades <-chan []*engine.Trade, ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case batch := <-trades:
+				tracker.Apply(batch)
+			}
+		}
+	}()
+}
+
+// WindowStart is the time of the oldest trade the current window covers.
+func (tracker *Tracker) WindowStart() time.Time {
+	return tracker.clock.Now().Add(-windowSize).Truncate(bucketSize)
+}
+
+func (tracker *Tracker) Apply(trades []*engine.Trade) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	cutoff := tracker.WindowStart()
+	for _, trade := range trades {
+		start := trade.ExecutedAt.UTC().Truncate(bucketSize)
+		if start.Before(cutoff) {
+			continue
+		}
+
+		buckets := tracker.buckets[trade.Symbol]
+		var current *bucket
+		for i := len(buckets) - 1; i >= 0; i-- {
+			if buckets[i].start.Equal(start) {
+				current = buckets[i]
+				break
+			}
+			if buckets[i].start.Before(start) {
+				break
+			}
+		}
+
+		if current == nil {
+			current = &bucket{start: start, firstAt: trade.ExecutedAt, open: trade.Price, high: trade.Price, low: trade.Price}
+			buckets = append(buckets, current)
+			sort.Slice(buckets, func(i, j int) bool { return buckets[i].start.Before(buckets[j].start) })
+		}
+
+		if trade.Price > current.high {
+			current.high = trade.Price
+		}
+		if trade.Price < current.low {
+			current.low = trade.Price
+		}
+		if trade.ExecutedAt.Before(current.firstAt) {
+			current.open = trade.Price
+			current.firstAt = trade.ExecutedAt
+		}
+		if !trade.ExecutedAt.Before(current.lastAt) {
+			current.close = trade.Price
+			current.closeQty = trade.Quantity
+			current.lastAt = trade.ExecutedAt
+		}
+		current.volume += trade.Quantity
+		current.quoteVolume += trade.Price * trade.Quantity
+		current.trades++
+
+		tracker.buckets[trade.Symbol] = evict(buckets, cutoff)
+	}
+}
+
+func evict(buckets []*bucket, cutoff time.Time) []*bucket {
+	i := 0
+	for i < len(buckets) && buckets[i].start.Before(cutoff) {
+		i++
+	}
+
+	return buckets[i:]
+}
+
+// Ticker returns the 24 hour statistics of symbol. A symbol without trades in
+// the window still reports its best bid and ask.
+func (tracker *Tracker) Ticker(symbol string) Ticker {
+	now := tracker.clock.Now()
+	cutoff := now.Add(-windowSize).Truncate(bucketSize)
+	ticker := Ticker{Symbol: symbol, OpenTime: cutoff, CloseTime: now}
+
+	tracker.mu.RLock()
+	for _, b := range tracker.buckets[symbol] {
+		if b.start.Before(cutoff) {
+			continue
+		}
+
+		if ticker.Trades == 0 {
+			ticker.OpenPrice = b.open
+			ticker.HighPrice = b.high
+			ticker.LowPrice = b.low
+		}
+		if b.high > ticker.HighPrice {
+			ticker.HighPrice = b.high
+		}
+		if b.low < ticker.LowPrice {
+			ticker.LowPrice = b.low
+		}
+		ticker.LastPrice = b.close
+		ticker.LastQuantity = b.closeQty
+		ticker.Volume += b.volume
+		ticker.QuoteVolume += b.quoteVolume
+		ticker.Trades += b.trades
+	}
+	tracker.mu.RUnlock()
+
+	if ticker.OpenPrice > 0 {
+		ticker.PriceChange = ticker.LastPrice - ticker.OpenPrice
+		ticker.PriceChangePercent = ticker.PriceChange / ticker.OpenPrice * 100
+	}
+	ticker.BestBid, ticker.BestAsk = tracker.books.BestPrices(symbol)
+
+	return ticker
+}
+
+// Tickers returns the statistics of every symbol that has a book or traded
+// within the window, sorted by symbol.
+func (tracker *Tracker) Tickers() []Ticker {
+	seen := make(map[string]bool)
+	for _, symbol := range tracker.books.Symbols() {
+		seen[symbol] = true
+	}
+
+	tracker.mu.RLock()
+	for symbol := range tracker.buckets {
+		seen[symbol] = true
+	}
+	tracker.mu.RUnlock()
+
+	symbols := make([]string, 0, len(seen))
+	for symbol := range seen {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	tickers := make([]Ticker, 0, len(symbols))
+	for _, symbol := range symbols {
+		tickers = append(tickers, tracker.Ticker(symbol))
+	}
+
+	return tickers
+}
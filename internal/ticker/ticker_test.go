@@ -0,0 +1,46 @@
+package ticker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+type fakeBooks struct{}
+
+func (fakeBooks) BestPrices(symbol string) (float64, float64) { return 99, 101 }
+func (fakeBooks) Symbols() []string                           { return []string{"SYM"} }
+
+func TestTracker_RollingWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := engine.NewManualClock(start)
+	tracker := NewTracker(fakeBooks{}, clock)
+
+	tracker.Apply([]*engine.Trade{
+		{Symbol: "SYM", Price: 100, Quantity: 1, ExecutedAt: start},
+		{Symbol: "SYM", Price: 120, Quantity: 1, ExecutedAt: start.Add(time.Hour)},
+	})
+	clock.Set(start.Add(2 * time.Hour))
+	tracker.Apply([]*engine.Trade{
+		{Symbol: "SYM", Price: 110, Quantity: 2, ExecutedAt: start.Add(2 * time.Hour)},
+	})
+
+	ticker := tracker.Ticker("SYM")
+	if ticker.OpenPrice != 100 || ticker.HighPrice != 120 || ticker.LowPrice != 100 || ticker.LastPrice != 110 {
+		t.Errorf("unexpected open/high/low/last %v/%v/%v/%v", ticker.OpenPrice, ticker.HighPrice, ticker.LowPrice, ticker.LastPrice)
+	}
+	if ticker.Volume != 4 || ticker.QuoteVolume != 440 || ticker.PriceChangePercent != 10 {
+		t.Errorf("unexpected volume %v quote %v change %v%%", ticker.Volume, ticker.QuoteVolume, ticker.PriceChangePercent)
+	}
+	if ticker.BestBid != 99 || ticker.BestAsk != 101 {
+		t.Errorf("expected best bid/ask from the book, got %v/%v", ticker.BestBid, ticker.BestAsk)
+	}
+
+	// the first print falls out of the window a day later
+	clock.Set(start.Add(24*time.Hour + time.Minute))
+	ticker = tracker.Ticker("SYM")
+	if ticker.OpenPrice != 120 || ticker.Volume != 3 {
+		t.Errorf("expected window to start at the 120 print with volume 3, got open %v volume %v", ticker.OpenPrice, ticker.Volume)
+	}
+}
@@ -0,0 +1,162 @@
+package ws
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// Binary frames start with a byte naming the message, followed by its fields
+// in order. Numbers are little-endian: prices, quantities and totals are
+// float64, seq is uint64, checksums are uint32, counts are uint16 and times
+// are int64 Unix nanoseconds. A string is a uint8 length and its bytes, and a
+// side is one byte, 0 for buy and 1 for sell. A message with a count too
+// large for a uint16, such as the full snapshot of a very deep book, is sent
+// as JSON instead.
+//
+//	depth_snapshot  1, book
+//	depth_update    2, symbol, count, count × (seq, side, price, qty, checksum)
+//	snapshot        3, count, count × book
+//	trade           4, id, symbol, price, quantity, taker side, executed at
+//	bbo             5, symbol, bid price, bid qty, ask price, ask qty, spread, mid
+//
+// A book is symbol, seq, checksum, a flags byte, the bid count and bids, then
+// the ask count and asks. Each level is price and qty, followed by total when
+// flag bit 0 (cumulative) is set.
+const (
+	binaryDepthSnapshot byte = 1
+	binaryDepthUpdate   byte = 2
+	binarySnapshot      byte = 3
+	binaryTrade         byte = 4
+	binaryBBO           byte = 5
+)
+
+const binaryCumulative byte = 1
+
+// encodeBinary returns the binary frame of a message payload, false for
+// payloads that only have a JSON form or whose counts don't fit.
+func encodeBinary(payload any) ([]byte, bool) {
+	if !binaryCountsFit(payload) {
+		return nil, false
+	}
+
+	var buf []byte
+	switch payload := payload.(type) {
+	case engine.BookDepth:
+		buf = appendBook(append(buf, binaryDepthSnapshot), payload)
+	case depthUpdate:
+		buf = appendString(append(buf, binaryDepthUpdate), payload.Symbol)
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(payload.Changes)))
+		for _, change := range payload.Changes {
+			buf = binary.LittleEndian.AppendUint64(buf, change.Seq)
+			buf = appendSide(buf, change.Side)
+			buf = appendFloat(buf, change.Price)
+			buf = appendFloat(buf, change.Qty)
+			buf = binary.LittleEndian.AppendUint32(buf, change.Checksum)
+		}
+	case map[string]engine.BookDepth:
+		symbols := make([]string, 0, len(payload))
+		for symbol := range payload {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+
+		buf = binary.LittleEndian.AppendUint16(append(buf, binarySnapshot), uint16(len(symbols)))
+		for _, symbol := range symbols {
+			buf = appendBook(buf, payload[symbol])
+		}
+	case tradeMessage:
+		buf = appendString(append(buf, binaryTrade), payload.ID)
+		buf = appendString(buf, payload.Symbol)
+		buf = appendFloat(buf, payload.Price)
+		buf = appendFloat(buf, payload.Quantity)
+		buf = appendSide(buf, payload.TakerSide)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(payload.ExecutedAt.UnixNano()))
+	case engine.BBO:
+		buf = appendString(append(buf, binaryBBO), payload.Symbol)
+		for _, value := range []float64{payload.BidPrice, payload.BidQty, payload.AskPrice, payload.AskQty, payload.Spread, payload.Mid} {
+			buf = appendFloat(buf, value)
+		}
+	default:
+		return nil, false
+	}
+
+	return buf, true
+}
+
+// binaryCountsFit reports whether every count in payload fits in a uint16.
+func binaryCountsFit(payload any) bool {
+	switch payload := payload.(type) {
+	case engine.BookDepth:
+		return bookCountsFit(payload)
+	case depthUpdate:
+		return len(payload.Changes) <= math.MaxUint16
+	case map[string]engine.BookDepth:
+		if len(payload) > math.MaxUint16 {
+			return false
+		}
+		for _, book := range payload {
+			if !bookCountsFit(book) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func bookCountsFit(book engine.BookDepth) bool {
+	return len(book.Bids) <= math.MaxUint16 && len(book.Asks) <= math.MaxUint16
+}
+
+func appendBook(buf []byte, book engine.BookDepth) []byte {
+	var flags byte
+	for _, levels := range [][]engine.DepthLevel{book.Bids, book.Asks} {
+		for _, level := range levels {
+			if level.Total != 0 {
+				flags = binaryCumulative
+			}
+		}
+	}
+
+	buf = appendString(buf, book.Symbol)
+	buf = binary.LittleEndian.AppendUint64(buf, book.Seq)
+	buf = binary.LittleEndian.AppendUint32(buf, book.Checksum)
+	buf = append(buf, flags)
+	for _, levels := range [][]engine.DepthLevel{book.Bids, book.Asks} {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(levels)))
+		for _, level := range levels {
+			buf = appendFloat(buf, level.Price)
+			buf = appendFloat(buf, level.Qty)
+			if flags&binaryCumulative != 0 {
+				buf = appendFloat(buf, level.Total)
+			}
+		}
+	}
+
+	return buf
+}
+
+// appendString writes value with a one byte length. Symbols and IDs are far
+// shorter than 255 bytes; anything longer is cut.
+func appendString(buf []byte, value string) []byte {
+	if len(value) > math.MaxUint8 {
+		value = value[:math.MaxUint8]
+	}
+
+	return append(append(buf, byte(len(value))), value...)
+}
+
+func appendFloat(buf []byte, value float64) []byte {
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(value))
+}
+
+func appendSide(buf []byte, side engine.Side) []byte {
+	if side == engine.Sell {
+		return append(buf, 1)
+	}
+
+	return append(buf, 0)
+}
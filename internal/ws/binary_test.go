@@ -0,0 +1,93 @@
+package ws
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gorilla/websocket"
+)
+
+func TestHub_BinarySubprotocol(t *testing.T) {
+	hub := NewWsHub()
+	dialer := &websocket.Dialer{Subprotocols: []string{BinarySubprotocol}, EnableCompression: true}
+	conn, response := dialHubWith(t, hub, dialer, "")
+	if conn.Subprotocol() != BinarySubprotocol {
+		t.Fatalf("expected the binary subprotocol, got %q", conn.Subprotocol())
+	}
+	if !strings.Contains(response.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Fatalf("expected permessage-deflate, got %q", response.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	plain := dialHub(t, hub, "")
+
+	subscribe := wsRequest{Op: subscribeOp, Channels: []string{ChannelBBO, ChannelTicker}, Symbols: []string{"BTC"}}
+	conn.WriteJSON(subscribe)
+	plain.WriteJSON(subscribe)
+	if msg := readMessage(t, conn); msg.Type != "subscribed" {
+		t.Fatalf("expected a JSON subscribed ack, got %+v", msg)
+	}
+	readMessage(t, plain)
+
+	bbo := engine.BBO{Symbol: "BTC", BidPrice: 99.5, BidQty: 2, AskPrice: 100.5, AskQty: 3, Spread: 1, Mid: 100}
+	hub.Publish(ChannelBBO, "BTC", NewMessage(ChannelBBO, bbo))
+	hub.Publish(ChannelTicker, "BTC", NewMessage(ChannelTicker, "ticker"))
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	frameType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read err: %v", err)
+	}
+	if frameType != websocket.BinaryMessage || len(data) != 2+len("BTC")+6*8 || data[0] != binaryBBO || string(data[2:5]) != "BTC" {
+		t.Fatalf("expected a binary bbo frame, got type %d % x", frameType, data)
+	}
+	if bid := math.Float64frombits(binary.LittleEndian.Uint64(data[5:])); bid != 99.5 {
+		t.Fatalf("expected bid price 99.5, got %v", bid)
+	}
+
+	if msg := readMessage(t, conn); msg.Type != ChannelTicker || msg.Payload != "ticker" {
+		t.Fatalf("expected messages without a binary form as JSON, got %+v", msg)
+	}
+	if msg := readMessage(t, plain); msg.Type != ChannelBBO || msg.Payload.(map[string]any)["bid_price"] != 99.5 {
+		t.Fatalf("expected other connections to keep JSON, got %+v", msg)
+	}
+}
+
+func TestEncodeBinary_DepthUpdate(t *testing.T) {
+	data, ok := encodeBinary(depthUpdate{Symbol: "ETH", Changes: []engine.LevelUpdate{{Seq: 9, Side: engine.Sell, Price: 10, Qty: 0, Checksum: 42}}})
+	if !ok {
+		t.Fatalf("expected depth updates to have a binary form")
+	}
+
+	want := []byte{binaryDepthUpdate, 3, 'E', 'T', 'H', 1, 0}
+	want = binary.LittleEndian.AppendUint64(want, 9)
+	want = append(want, 1)
+	want = binary.LittleEndian.AppendUint64(want, math.Float64bits(10))
+	want = binary.LittleEndian.AppendUint64(want, 0)
+	want = binary.LittleEndian.AppendUint32(want, 42)
+	if string(data) != string(want) {
+		t.Fatalf("expected % x, got % x", want, data)
+	}
+}
+
+func TestEncodeBinary_FallsBackToJSONForDeepBooks(t *testing.T) {
+	levels := make([]engine.DepthLevel, math.MaxUint16+1)
+	deep := engine.BookDepth{Symbol: "BTC", Bids: levels, Asks: []engine.DepthLevel{}}
+	if _, ok := encodeBinary(deep); ok {
+		t.Fatalf("expected no binary form for a book with more levels than a uint16 counts")
+	}
+	if _, ok := encodeBinary(map[string]engine.BookDepth{"BTC": deep}); ok {
+		t.Fatalf("expected no binary form for a snapshot holding a deep book")
+	}
+
+	frame, ok := NewMessage("depth_snapshot", deep).frame(encodingBinary)
+	if !ok || frame.binary || !strings.HasPrefix(string(frame.data), `{"type":"depth_snapshot"`) {
+		t.Fatalf("expected the snapshot as JSON, got binary=%v %.40q", frame.binary, frame.data)
+	}
+
+	if _, ok := encodeBinary(engine.BookDepth{Symbol: "BTC", Bids: levels[:math.MaxUint16]}); !ok {
+		t.Fatalf("expected a book at the count limit to stay binary")
+	}
+}
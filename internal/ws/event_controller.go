@@ -9,4 +9,5 @@ func HandleEventController(r *gin.Engine, e *engine.Engine, h *WsHub) {
 	r.GET("/event", func(c *gin.Context) {
 		h.HandleWs(c)
 	})
+	r.GET("/stream", h.HandleStream)
 }
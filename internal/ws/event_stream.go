@@ -0,0 +1,134 @@
+package ws
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/api"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleStream serves the hub's messages as Server-Sent Events, for clients
+// that can't keep a WebSocket open. The channels and symbols query
+// parameters take comma-separated lists and stand in for a subscribe
+// request; depth, aggregation and cumulative work as on /event. Each event's
+// data is the same JSON message a WebSocket client gets.
+//
+// Published messages carry an event ID. A client that reconnects with
+// Last-Event-ID gets the messages it missed from the replay buffer instead of
+// fresh snapshots, as long as the buffer still holds all of them and the ID
+// was issued since this process started. The orders channel takes an API key
+// as on /event; its messages are not replayed.
+func (hub *WsHub) HandleStream(c *gin.Context) {
+	depth, err := api.ParseDepthOptions(c)
+	if err != nil {
+		api.WriteError(c, err)
+		return
+	}
+
+	subscription, err := parseSubscription(wsRequest{Channels: splitList(c.Query("channels")), Symbols: splitList(c.Query("symbols"))})
+	if err != nil {
+		api.WriteError(c, err)
+		return
+	}
+
+	account, err := api.Authenticate(c, hub.keys)
+	if err != nil {
+		api.WriteError(c, err)
+		return
+	}
+	if subscription.private() && account == "" {
+		api.WriteError(c, errPrivateChannel)
+		return
+	}
+
+	// An ID from an earlier process, or one that isn't an event ID at all,
+	// can't be resumed from, so the stream starts over with snapshots.
+	lastID, resume := hub.replay.parseEventID(c.GetHeader("Last-Event-ID"))
+
+	client := newHubClient(nil, account, depth)
+	hub.attach(client, subscription, lastID, resume)
+	defer hub.remove(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	controller := http.NewResponseController(c.Writer)
+	keepalive := time.NewTicker(pingPeriod)
+	defer keepalive.Stop()
+
+	for {
+		var frame string
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg, ok := <-client.send:
+			if !ok {
+				return
+			}
+			frame = hub.eventFrame(msg)
+		case <-keepalive.C:
+			frame = ": keepalive\n\n"
+		}
+
+		_ = controller.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := c.Writer.WriteString(frame); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// attach registers a stream client with its subscription. A resumed stream
+// gets the buffered messages it missed; any other gets the usual snapshots.
+func (hub *WsHub) attach(client *hubClient, subscription subscriptionPayload, lastID uint64, resume bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.clients[client] = true
+	client.subscriptions.add(subscription)
+
+	if resume {
+		if missed, ok := hub.replay.since(lastID); ok {
+			for _, entry := range missed {
+				if entry.matches(client) {
+					hub.deliver(client, entry.message, entry.id)
+				}
+			}
+			return
+		}
+	}
+
+	hub.sendSnapshots(client, subscription)
+}
+
+// eventFrame formats msg as one event. Messages are compact JSON, so the data
+// always fits on a single line.
+func (hub *WsHub) eventFrame(msg hubMessage) string {
+	if msg.id == 0 {
+		return "data: " + string(msg.data) + "\n\n"
+	}
+
+	return fmt.Sprintf("id: %s\ndata: %s\n\n", hub.replay.eventID(msg.id), msg.data)
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,139 @@
+package ws
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestStream(t *testing.T) (*WsHub, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	hub := NewWsHub()
+	r := gin.New()
+	r.GET("/stream", hub.HandleStream)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return hub, server
+}
+
+func openStream(t *testing.T, url string, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("request err: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+
+	return response, bufio.NewReader(response.Body)
+}
+
+// readEvent returns the id and data lines of the next event.
+func readEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+
+	lines := make(chan []string, 1)
+	go func() {
+		var event []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				lines <- nil
+				return
+			}
+			if line = strings.TrimRight(line, "\n"); line == "" {
+				lines <- event
+				return
+			}
+			event = append(event, line)
+		}
+	}()
+
+	var id, data string
+	select {
+	case event := <-lines:
+		for _, line := range event {
+			if value, ok := strings.CutPrefix(line, "id: "); ok {
+				id = value
+			}
+			if value, ok := strings.CutPrefix(line, "data: "); ok {
+				data = value
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for an event")
+	}
+
+	return id, data
+}
+
+func TestStream_ResumesFromLastEventID(t *testing.T) {
+	hub, server := newTestStream(t)
+
+	response, reader := openStream(t, server.URL+"/stream?channels=trades&symbols=btc", "")
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", response.Header.Get("Content-Type"))
+	}
+
+	hub.Publish(ChannelTrades, "ETH", NewMessage("trade", "eth"))
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "first"))
+	id, data := readEvent(t, reader)
+	if id != hub.replay.eventID(2) || !strings.Contains(data, "first") {
+		t.Fatalf("expected event 2 with the first BTC trade, got %q %q", id, data)
+	}
+	response.Body.Close()
+
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "second"))
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "third"))
+
+	_, reader = openStream(t, server.URL+"/stream?channels=trades&symbols=BTC", id)
+	for _, want := range []string{"second", "third"} {
+		if _, data := readEvent(t, reader); !strings.Contains(data, want) {
+			t.Fatalf("expected missed %s trade, got %q", want, data)
+		}
+	}
+}
+
+func TestStream_StartsOverOnIDFromAnotherProcess(t *testing.T) {
+	hub, server := newTestStream(t)
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "missed"))
+
+	for _, lastEventID := range []string{"0-1", "1", "bogus"} {
+		response, reader := openStream(t, server.URL+"/stream?channels=trades&symbols=BTC", lastEventID)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", lastEventID, response.StatusCode)
+		}
+
+		hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "live "+lastEventID))
+		if _, data := readEvent(t, reader); !strings.Contains(data, "live "+lastEventID) {
+			t.Fatalf("%s: expected no replay, got %q", lastEventID, data)
+		}
+		response.Body.Close()
+	}
+}
+
+func TestStream_RejectsUnknownChannel(t *testing.T) {
+	_, server := newTestStream(t)
+
+	response, err := http.Get(server.URL + "/stream?channels=news&symbols=BTC")
+	if err != nil {
+		t.Fatalf("request err: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,125 @@
+package ws
+
+import (
+	"math"
+	"sort"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// levelBook is the book worker's copy of one book's price levels. Keeping it
+// up to date from the book updates lets the worker work out the checksum of
+// every depth change, which the matching loop leaves out to stay fast.
+type levelBook struct {
+	seq  uint64
+	bids levelSide
+	asks levelSide
+}
+
+// levelSide holds one side's levels, prices sorted best first.
+type levelSide struct {
+	prices []float64
+	qty    map[float64]float64
+	better func(price float64, other float64) bool
+}
+
+func newLevelBook(depth engine.BookDepth) *levelBook {
+	book := &levelBook{
+		seq:  depth.Seq,
+		bids: levelSide{qty: map[float64]float64{}, better: func(price float64, other float64) bool { return price > other }},
+		asks: levelSide{qty: map[float64]float64{}, better: func(price float64, other float64) bool { return price < other }},
+	}
+	for _, level := range depth.Bids {
+		book.bids.set(level.Price, level.Qty)
+	}
+	for _, level := range depth.Asks {
+		book.asks.set(level.Price, level.Qty)
+	}
+
+	return book
+}
+
+// apply sets the level a change touches and returns the checksum of the book
+// after it.
+func (book *levelBook) apply(change engine.LevelUpdate) uint32 {
+	book.seq = change.Seq
+	if change.Side == engine.Buy {
+		book.bids.set(change.Price, change.Qty)
+	} else {
+		book.asks.set(change.Price, change.Qty)
+	}
+
+	return engine.ChecksumLevels(book.bids.top(), book.asks.top())
+}
+
+func (side *levelSide) set(price float64, qty float64) {
+	i := sort.Search(len(side.prices), func(i int) bool { return !side.better(side.prices[i], price) })
+	found := i < len(side.prices) && side.prices[i] == price
+
+	switch {
+	case qty > 0 && !found:
+		side.prices = append(side.prices, 0)
+		copy(side.prices[i+1:], side.prices[i:])
+		side.prices[i] = price
+	case qty <= 0 && found:
+		side.prices = append(side.prices[:i], side.prices[i+1:]...)
+	}
+
+	if qty > 0 {
+		side.qty[price] = qty
+	} else {
+		delete(side.qty, price)
+	}
+}
+
+func (side *levelSide) top() []engine.DepthLevel {
+	levels := make([]engine.DepthLevel, 0, min(len(side.prices), engine.ChecksumDepth))
+	for _, price := range side.prices[:min(len(side.prices), engine.ChecksumDepth)] {
+		levels = append(levels, engine.DepthLevel{Price: price, Qty: side.qty[price]})
+	}
+
+	return levels
+}
+
+// fullDepth asks for every level of a book.
+var fullDepth = engine.DepthOptions{Depth: math.MaxInt}
+
+// levelBooks follows the levels of every book. Books are loaded from the
+// engine when the worker starts, and again whenever the feed skips a seq,
+// since the copy can't be trusted after a dropped update.
+type levelBooks struct {
+	matchEngine *engine.Engine
+	books       map[string]*levelBook
+}
+
+func newLevelBooks(matchEngine *engine.Engine) *levelBooks {
+	books := &levelBooks{matchEngine: matchEngine, books: map[string]*levelBook{}}
+	for symbol, depth := range matchEngine.Depths(fullDepth) {
+		books.books[symbol] = newLevelBook(depth)
+	}
+
+	return books
+}
+
+// change returns the level change of update with its checksum, false if the
+// book already includes it.
+func (books *levelBooks) change(update engine.BookUpdate) (engine.LevelUpdate, bool) {
+	book, ok := books.books[update.Symbol]
+	if !ok {
+		// A book created after the worker started begins empty at seq 0.
+		book = newLevelBook(engine.BookDepth{Symbol: update.Symbol})
+		books.books[update.Symbol] = book
+	}
+	if update.Seq > book.seq+1 {
+		depth, _ := books.matchEngine.Depth(update.Symbol, fullDepth)
+		book = newLevelBook(depth)
+		books.books[update.Symbol] = book
+	}
+	if update.Seq <= book.seq {
+		return engine.LevelUpdate{}, false
+	}
+
+	change := update.Level()
+	change.Checksum = book.apply(change)
+	return change, true
+}
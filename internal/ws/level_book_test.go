@@ -0,0 +1,41 @@
+package ws
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+func TestLevelBooks_ChecksumEveryChange(t *testing.T) {
+	e := engine.NewEngine(nil)
+	e.Setup(map[string]*engine.OrderBook{"SYM": engine.NewOrderBook("SYM")})
+	e.GetBook("SYM").AddOrder(&engine.Order{ID: "b1", Symbol: "SYM", Side: engine.Buy, Price: 100, Quantity: 1, Remaining: 1})
+	books := newLevelBooks(e)
+	if _, ok := books.change(engine.BookUpdate{Seq: 1, Symbol: "SYM", Side: engine.Buy, Price: 100, LevelQty: 1}); ok {
+		t.Errorf("expected a change the copy was loaded with to be skipped")
+	}
+
+	steps := []struct {
+		update engine.BookUpdate
+		want   string
+	}{
+		{engine.BookUpdate{Seq: 2, Symbol: "SYM", Side: engine.Sell, Price: 101, LevelQty: 1}, "100:1:101:1"},
+		{engine.BookUpdate{Seq: 3, Symbol: "SYM", Side: engine.Sell, Price: 102, LevelQty: 1}, "100:1:101:1:102:1"},
+		{engine.BookUpdate{Seq: 4, Symbol: "SYM", Side: engine.Sell, Price: 101, LevelQty: 0}, "100:1:102:1"},
+		{engine.BookUpdate{Seq: 1, Symbol: "NEW", Side: engine.Buy, Price: 5, LevelQty: 2}, "5:2"},
+	}
+	for i, step := range steps {
+		change, ok := books.change(step.update)
+		if want := crc32.ChecksumIEEE([]byte(step.want)); !ok || change.Checksum != want || change.Seq != step.update.Seq {
+			t.Errorf("step %d: expected checksum of %q, got %+v", i, step.want, change)
+		}
+	}
+
+	// After a gap the copy is reloaded from the engine, which has no asks.
+	e.GetBook("SYM").AddOrder(&engine.Order{ID: "b2", Symbol: "SYM", Side: engine.Buy, Price: 99, Quantity: 4, Remaining: 4})
+	change, ok := books.change(engine.BookUpdate{Seq: 9, Symbol: "SYM", Side: engine.Sell, Price: 103, LevelQty: 1})
+	if want := crc32.ChecksumIEEE([]byte("100:1:103:1:99:4")); !ok || change.Checksum != want {
+		t.Errorf("expected the reloaded book's checksum, got %+v", change)
+	}
+}
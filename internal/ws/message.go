@@ -0,0 +1,68 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// encoding is the wire format a connection receives published messages in.
+type encoding int
+
+const (
+	encodingJSON encoding = iota
+	encodingBinary
+)
+
+// BinarySubprotocol is the WebSocket subprotocol that selects the binary
+// encoding of depth, trade and bbo messages.
+const BinarySubprotocol = "orderbook.binary.v1"
+
+// Message is a message published through the hub. It is encoded at most once
+// per wire format, when the first client that needs that format receives it,
+// and every other client shares the encoded frame, compressed frames
+// included. Messages without a binary form go to binary clients as JSON.
+type Message struct {
+	msgType string
+	payload any
+	frames  [2]*hubMessage
+}
+
+func NewMessage(msgType string, payload any) *Message {
+	return &Message{msgType: msgType, payload: payload}
+}
+
+// frame returns msg encoded as enc, false if it can't be encoded. Frames are
+// built lazily, so callers must hold hub.mu.
+func (msg *Message) frame(enc encoding) (hubMessage, bool) {
+	if cached := msg.frames[enc]; cached != nil {
+		return *cached, true
+	}
+
+	frame := hubMessage{}
+	if enc == encodingBinary {
+		if data, ok := encodeBinary(msg.payload); ok {
+			frame = hubMessage{data: data, binary: true}
+		}
+	}
+	if frame.data == nil {
+		data, err := json.Marshal(wsMessage{Type: msg.msgType, Payload: msg.payload})
+		if err != nil {
+			log.Println(msg.msgType, "marshal error:", err)
+			return hubMessage{}, false
+		}
+		frame.data = data
+	}
+
+	frameType := websocket.TextMessage
+	if frame.binary {
+		frameType = websocket.BinaryMessage
+	}
+	if prepared, err := websocket.NewPreparedMessage(frameType, frame.data); err == nil {
+		frame.prepared = prepared
+	}
+
+	msg.frames[enc] = &frame
+	return frame, true
+}
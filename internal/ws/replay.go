@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// replayBufferSize is how many published messages the hub keeps for event
+// streams that reconnect with a Last-Event-ID.
+const replayBufferSize = 4096
+
+type replayEntry struct {
+	id      uint64
+	message *Message
+	channel string
+	symbol  string
+	// deltas marks depth updates, which only go to clients that stream
+	// depth deltas.
+	deltas bool
+}
+
+func (entry replayEntry) matches(client *hubClient) bool {
+	if entry.deltas && !client.streamsDepth() {
+		return false
+	}
+
+	return client.subscriptions.has(entry.channel, entry.symbol)
+}
+
+// replayBuffer numbers every published message and keeps the latest size of
+// them, oldest first. Numbers restart with the process, so the event IDs
+// handed to clients carry the buffer's epoch to tell them apart.
+type replayBuffer struct {
+	entries []replayEntry
+	lastID  uint64
+	size    int
+	epoch   string
+}
+
+func newReplayBuffer(size int) replayBuffer {
+	return replayBuffer{size: size, epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// eventID formats id as epoch-id.
+func (buffer *replayBuffer) eventID(id uint64) string {
+	return buffer.epoch + "-" + strconv.FormatUint(id, 10)
+}
+
+// parseEventID returns the number in an event ID, false if the ID wasn't
+// issued by this buffer, as after a restart.
+func (buffer *replayBuffer) parseEventID(value string) (uint64, bool) {
+	epoch, number, ok := strings.Cut(value, "-")
+	if !ok || epoch != buffer.epoch {
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(number, 10, 64)
+	return id, err == nil
+}
+
+func (buffer *replayBuffer) add(channel string, symbol string, deltas bool, msg *Message) replayEntry {
+	buffer.lastID++
+	entry := replayEntry{id: buffer.lastID, message: msg, channel: channel, symbol: symbol, deltas: deltas}
+
+	buffer.entries = append(buffer.entries, entry)
+	if len(buffer.entries) > buffer.size {
+		buffer.entries = buffer.entries[len(buffer.entries)-buffer.size:]
+	}
+
+	return entry
+}
+
+// since returns the entries published after id, false if some of them have
+// already been dropped or id was never handed out.
+func (buffer *replayBuffer) since(id uint64) ([]replayEntry, bool) {
+	if id > buffer.lastID {
+		return nil, false
+	}
+
+	first := buffer.lastID - uint64(len(buffer.entries)) + 1
+	if id+1 < first {
+		return nil, false
+	}
+
+	return buffer.entries[id+1-first:], true
+}
@@ -0,0 +1,151 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cemsubasi/orderbook/internal/api"
+)
+
+const (
+	ChannelDepth  = "depth"
+	ChannelTrades = "trades"
+	ChannelTicker = "ticker"
+	ChannelBBO    = "bbo"
+	ChannelL3     = "l3"
+	// ChannelOrders is private: it carries the execution reports of the
+	// connection's own account.
+	ChannelOrders = "orders"
+)
+
+// AllSymbols subscribes to a channel for every symbol, including ones that
+// only get a book after the subscription was made.
+const AllSymbols = "*"
+
+const (
+	subscribeOp   = "subscribe"
+	unsubscribeOp = "unsubscribe"
+	placeOrderOp  = "place_order"
+	cancelOrderOp = "cancel_order"
+	amendOrderOp  = "amend_order"
+)
+
+var channels = map[string]bool{
+	ChannelDepth:  true,
+	ChannelTrades: true,
+	ChannelTicker: true,
+	ChannelBBO:    true,
+	ChannelL3:     true,
+	ChannelOrders: true,
+}
+
+// wsRequest is a message sent by a client. ID is optional and is echoed on
+// the reply so clients can match them up. Channels and Symbols are used by
+// subscriptions, the order fields by order entry.
+type wsRequest struct {
+	ID       string   `json:"id,omitempty"`
+	Op       string   `json:"op"`
+	Channels []string `json:"channels"`
+	Symbols  []string `json:"symbols"`
+	api.OrderCreateRequest
+	OrderID string `json:"order_id,omitempty"`
+}
+
+type wsMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Payload any    `json:"payload"`
+}
+
+type subscriptionPayload struct {
+	Channels []string `json:"channels"`
+	Symbols  []string `json:"symbols"`
+}
+
+type errorPayload struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Param string `json:"param,omitempty"`
+}
+
+// newErrorPayload reports err the way the REST API would, hiding the text of
+// anything that isn't an *api.Error.
+func newErrorPayload(err error) errorPayload {
+	var apiErr *api.Error
+	if !errors.As(err, &apiErr) {
+		return errorPayload{Error: "internal error", Code: api.CodeInternal}
+	}
+
+	return errorPayload{Error: apiErr.Message, Code: apiErr.Code, Param: apiErr.Param}
+}
+
+// subscriptions maps a channel to the symbols followed on it.
+type subscriptions map[string]map[string]bool
+
+func (subs subscriptions) has(channel string, symbol string) bool {
+	symbols := subs[channel]
+	return symbols[symbol] || symbols[AllSymbols]
+}
+
+func (subs subscriptions) add(subscription subscriptionPayload) {
+	for _, channel := range subscription.Channels {
+		if subs[channel] == nil {
+			subs[channel] = map[string]bool{}
+		}
+		for _, symbol := range subscription.Symbols {
+			subs[channel][symbol] = true
+		}
+	}
+}
+
+func (subscription subscriptionPayload) private() bool {
+	for _, channel := range subscription.Channels {
+		if channel == ChannelOrders {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errPrivateChannel rejects subscriptions to the orders channel from clients
+// that didn't authenticate when they connected.
+var errPrivateChannel = &api.Error{Status: http.StatusUnauthorized, Code: api.CodeUnauthorized, Message: "the orders channel needs an API key when connecting"}
+
+// invalidSubscription is reported to WebSocket clients as an error message
+// and to event stream requests as a 400 naming the query parameter.
+func invalidSubscription(param string, message string) error {
+	return &api.Error{Status: http.StatusBadRequest, Code: api.CodeInvalidParameter, Param: param, Message: message}
+}
+
+// parseSubscription checks the channels and symbols of a subscribe or
+// unsubscribe request and returns them normalized.
+func parseSubscription(request wsRequest) (subscriptionPayload, error) {
+	if len(request.Channels) == 0 {
+		return subscriptionPayload{}, invalidSubscription("channels", "channels is required")
+	}
+	if len(request.Symbols) == 0 {
+		return subscriptionPayload{}, invalidSubscription("symbols", "symbols is required, use \"*\" for every symbol")
+	}
+
+	payload := subscriptionPayload{Channels: request.Channels, Symbols: make([]string, 0, len(request.Symbols))}
+	for _, channel := range request.Channels {
+		if !channels[channel] {
+			return subscriptionPayload{}, invalidSubscription("channels", "unknown channel "+channel+", expected depth, trades, ticker, bbo, l3 or orders")
+		}
+	}
+	for _, symbol := range request.Symbols {
+		if symbol == AllSymbols {
+			payload.Symbols = append(payload.Symbols, symbol)
+			continue
+		}
+
+		normalized, err := api.ParseSymbol(symbol)
+		if err != nil {
+			return subscriptionPayload{}, invalidSubscription("symbols", err.Error())
+		}
+		payload.Symbols = append(payload.Symbols, normalized)
+	}
+
+	return payload, nil
+}
@@ -1,39 +1,431 @@
 package ws
 
 import (
+	"encoding/json"
+	"log"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/cemsubasi/orderbook/internal/api"
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/cemsubasi/orderbook/internal/engine"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	maxRequestSize = 64 << 10
+
+	// A connection that can't take a message within writeWait, or doesn't
+	// answer a ping within pongWait, is closed.
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	// sendQueueSize bounds the messages waiting for one connection. A client
+	// that falls this far behind is disconnected rather than slowing the
+	// publishers down; it can reconnect and resubscribe for fresh snapshots.
+	sendQueueSize = 1024
+)
+
+// hubMessage is a message queued for a client. ID is set on messages kept in
+// the replay buffer, which stream clients can resume from. Published messages
+// also carry a prepared frame, which the connections that share it compress
+// only once.
+type hubMessage struct {
+	id       uint64
+	data     []byte
+	binary   bool
+	prepared *websocket.PreparedMessage
+}
+
+// hubClient is a WebSocket connection or an event stream. Only WebSocket
+// clients have a conn; an event stream is drained by its request handler.
+type hubClient struct {
+	conn          *websocket.Conn
+	account       string
+	encoding      encoding
+	send          chan hubMessage
+	depth         engine.DepthOptions
+	subscriptions subscriptions
+	// orders holds the symbol of each order placed on this connection that
+	// is still live.
+	orders map[string]string
+	// cancelOnDisconnect asks for those orders to be cancelled once the
+	// connection is gone.
+	cancelOnDisconnect bool
+}
+
+func newHubClient(conn *websocket.Conn, account string, depth engine.DepthOptions) *hubClient {
+	return &hubClient{conn: conn, account: account, send: make(chan hubMessage, sendQueueSize), depth: depth, subscriptions: subscriptions{}, orders: map[string]string{}}
+}
+
+// writePump owns all writes to the connection: it drains the send queue and
+// pings the client, and closes the connection once the queue is closed or a
+// write fails.
+func (client *hubClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-client.send:
+			_ = client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := client.writeMessage(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (client *hubClient) writeMessage(msg hubMessage) error {
+	if msg.prepared != nil {
+		return client.conn.WritePreparedMessage(msg.prepared)
+	}
+	if msg.binary {
+		return client.conn.WriteMessage(websocket.BinaryMessage, msg.data)
+	}
+
+	return client.conn.WriteMessage(websocket.TextMessage, msg.data)
+}
+
+// streamsDepth reports whether the client's depth subscriptions are served as
+// a snapshot followed by level deltas. Grouped or cumulative levels can't be
+// patched by a single level change, so those clients get periodic snapshots.
+func (client *hubClient) streamsDepth() bool {
+	return streamsDepth(client.depth)
+}
+
+func streamsDepth(options engine.DepthOptions) bool {
+	return options.Aggregation == 0 && !options.Cumulative
+}
+
+// SnapshotFunc returns the messages that bring a new subscriber of symbol on
+// a channel up to date. symbol may be AllSymbols.
+type SnapshotFunc func(symbol string, depth engine.DepthOptions) []*Message
+
 type WsHub struct {
-	clients map[*websocket.Conn]bool
-	mu      sync.Mutex
+	clients   map[*hubClient]bool
+	snapshots map[string]SnapshotFunc
+	replay    replayBuffer
+	keys      auth.Keys
+	orders    OrderEntry
+	// sessions maps the live orders placed over the hub to their client.
+	sessions map[string]*hubClient
+	mu       sync.Mutex
 }
 
 func NewWsHub() *WsHub {
-	return &WsHub{clients: make(map[*websocket.Conn]bool)}
+	return &WsHub{
+		clients:   make(map[*hubClient]bool),
+		snapshots: make(map[string]SnapshotFunc),
+		replay:    newReplayBuffer(replayBufferSize),
+		sessions:  make(map[string]*hubClient),
+	}
+}
+
+// UseKeys sets the API keys that clients authenticate with to use the orders
+// channel. It must be called before the hub serves connections.
+func (hub *WsHub) UseKeys(keys auth.Keys) {
+	hub.keys = keys
 }
 
-func (hub *WsHub) Broadcast(msg []byte) {
+// SetSnapshot registers what a client receives right after subscribing to
+// channel, for channels that otherwise only send changes.
+func (hub *WsHub) SetSnapshot(channel string, snapshot SnapshotFunc) {
 	hub.mu.Lock()
 	defer hub.mu.Unlock()
-	for c := range hub.clients {
-		_ = c.WriteMessage(websocket.TextMessage, msg)
+
+	hub.snapshots[channel] = snapshot
+}
+
+// Publish queues msg for the clients subscribed to symbol on channel.
+func (hub *WsHub) Publish(channel string, symbol string, msg *Message) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	entry := hub.replay.add(channel, symbol, false, msg)
+	for client := range hub.clients {
+		if entry.matches(client) {
+			hub.deliver(client, entry.message, entry.id)
+		}
+	}
+}
+
+// PublishDepthUpdate sends msg to the depth subscribers of symbol that are
+// kept up to date with level deltas.
+func (hub *WsHub) PublishDepthUpdate(symbol string, msg *Message) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	entry := hub.replay.add(ChannelDepth, symbol, true, msg)
+	for client := range hub.clients {
+		if entry.matches(client) {
+			hub.deliver(client, entry.message, entry.id)
+		}
+	}
+}
+
+// PublishDepth sends every depth subscriber with grouped or cumulative
+// levels a snapshot message holding the books it follows, built with its own
+// depth options. depths is called once per distinct set of options.
+func (hub *WsHub) PublishDepth(depths func(options engine.DepthOptions) map[string]engine.BookDepth) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	built := map[engine.DepthOptions]map[string]engine.BookDepth{}
+	for client := range hub.clients {
+		if client.streamsDepth() || len(client.subscriptions[ChannelDepth]) == 0 {
+			continue
+		}
+
+		books, ok := built[client.depth]
+		if !ok {
+			books = depths(client.depth)
+			built[client.depth] = books
+		}
+
+		snapshot := map[string]engine.BookDepth{}
+		for symbol, book := range books {
+			if client.subscriptions.has(ChannelDepth, symbol) {
+				snapshot[symbol] = book
+			}
+		}
+
+		hub.deliver(client, NewMessage("snapshot", snapshot), 0)
 	}
 }
 
-var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+// upgrader offers permessage-deflate to clients that ask for it, and the
+// binary encoding to those that request BinarySubprotocol.
+var upgrader = websocket.Upgrader{
+	CheckOrigin:       func(r *http.Request) bool { return true },
+	Subprotocols:      []string{BinarySubprotocol},
+	EnableCompression: true,
+}
 
+// HandleWs accepts depth, aggregation and cumulative query parameters, which
+// shape the depth messages sent on this connection the same way they do for
+// GET /orderbook. The connection receives nothing until it subscribes. An API
+// key sent with the handshake, as for POST /orders, ties the connection to an
+// account and opens the orders channel to it.
+//
+// With cancel_on_disconnect=true, which needs an API key, the orders placed
+// on the connection are cancelled when it closes, however that happens.
+//
+// Clients that negotiate BinarySubprotocol get depth, trade and bbo messages
+// as binary frames; everything else stays JSON text.
 func (hub *WsHub) HandleWs(c *gin.Context) {
+	depth, err := api.ParseDepthOptions(c)
+	if err != nil {
+		api.WriteError(c, err)
+		return
+	}
+
+	account, err := api.Authenticate(c, hub.keys)
+	if err != nil {
+		api.WriteError(c, err)
+		return
+	}
+
+	cancelOnDisconnect, err := api.ParseFlag(c, "cancel_on_disconnect")
+	if err != nil {
+		api.WriteError(c, err)
+		return
+	}
+	if cancelOnDisconnect && account == "" {
+		api.WriteError(c, errCancelOnDisconnect)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return
 	}
+	conn.SetReadLimit(maxRequestSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
 
+	client := newHubClient(conn, account, depth)
+	client.cancelOnDisconnect = cancelOnDisconnect
+	if conn.Subprotocol() == BinarySubprotocol {
+		client.encoding = encodingBinary
+	}
 	hub.mu.Lock()
-	hub.clients[conn] = true
+	hub.clients[client] = true
 	hub.mu.Unlock()
+
+	go client.writePump()
+	defer hub.disconnect(client)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request wsRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			hub.reply(client, wsMessage{Type: "error", Payload: errorPayload{Error: "messages must be JSON requests", Code: api.CodeInvalidBody}})
+			continue
+		}
+
+		hub.handleRequest(client, request)
+	}
+}
+
+func (hub *WsHub) handleRequest(client *hubClient, request wsRequest) {
+	switch request.Op {
+	case subscribeOp, unsubscribeOp:
+		subscription, err := parseSubscription(request)
+		if err != nil {
+			hub.reply(client, wsMessage{Type: "error", ID: request.ID, Payload: newErrorPayload(err)})
+			return
+		}
+
+		if request.Op == subscribeOp && subscription.private() && client.account == "" {
+			hub.reply(client, wsMessage{Type: "error", ID: request.ID, Payload: newErrorPayload(errPrivateChannel)})
+			return
+		}
+
+		if request.Op == subscribeOp {
+			hub.subscribe(client, request.ID, subscription)
+		} else {
+			hub.unsubscribe(client, request.ID, subscription)
+		}
+	case placeOrderOp, cancelOrderOp, amendOrderOp:
+		hub.handleOrderRequest(client, request)
+	default:
+		hub.reply(client, wsMessage{Type: "error", ID: request.ID, Payload: errorPayload{Error: "op must be one of subscribe, unsubscribe, place_order, cancel_order, amend_order", Code: api.CodeInvalidParameter, Param: "op"}})
+	}
+}
+
+// subscribe acknowledges the subscription and then sends the snapshots of
+// the channels that have one, all while holding the hub lock so that no
+// update published in between can reach the client ahead of its snapshot.
+func (hub *WsHub) subscribe(client *hubClient, id string, subscription subscriptionPayload) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	client.subscriptions.add(subscription)
+	hub.write(client, wsMessage{Type: "subscribed", ID: id, Payload: subscription})
+	hub.sendSnapshots(client, subscription)
+}
+
+// sendSnapshots queues the snapshots of the subscribed channels that have
+// one. The caller must hold hub.mu.
+func (hub *WsHub) sendSnapshots(client *hubClient, subscription subscriptionPayload) {
+	for _, channel := range subscription.Channels {
+		snapshot := hub.snapshots[channel]
+		if snapshot == nil {
+			continue
+		}
+		for _, symbol := range subscription.Symbols {
+			for _, msg := range snapshot(symbol, client.depth) {
+				hub.deliver(client, msg, 0)
+			}
+		}
+	}
+}
+
+func (hub *WsHub) unsubscribe(client *hubClient, id string, subscription subscriptionPayload) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for _, channel := range subscription.Channels {
+		for _, symbol := range subscription.Symbols {
+			delete(client.subscriptions[channel], symbol)
+		}
+		if len(client.subscriptions[channel]) == 0 {
+			delete(client.subscriptions, channel)
+		}
+	}
+
+	hub.write(client, wsMessage{Type: "unsubscribed", ID: id, Payload: subscription})
+}
+
+func (hub *WsHub) reply(client *hubClient, msg wsMessage) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.write(client, msg)
+}
+
+// write queues msg for one client. The caller must hold hub.mu.
+func (hub *WsHub) write(client *hubClient, msg wsMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("ws message marshal error:", err)
+		return
+	}
+
+	hub.enqueue(client, hubMessage{data: payload})
+}
+
+// deliver queues a published message for one client in the client's
+// encoding, with the replay ID it was given, if any. The caller must hold
+// hub.mu.
+func (hub *WsHub) deliver(client *hubClient, msg *Message, id uint64) {
+	frame, ok := msg.frame(client.encoding)
+	if !ok {
+		return
+	}
+	frame.id = id
+
+	hub.enqueue(client, frame)
+}
+
+// enqueue hands msg to the client's writer without waiting, and disconnects
+// the client if its queue is full. Queueing under hub.mu keeps every client's
+// messages in publish order, so a snapshot still precedes the updates that
+// follow it. The caller must hold hub.mu.
+func (hub *WsHub) enqueue(client *hubClient, msg hubMessage) {
+	if !hub.clients[client] {
+		return
+	}
+
+	select {
+	case client.send <- msg:
+	default:
+		log.Println("ws client too slow, disconnecting")
+		hub.removeLocked(client)
+	}
+}
+
+func (hub *WsHub) remove(client *hubClient) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.removeLocked(client)
+}
+
+// removeLocked drops the client and closes its queue. A WebSocket writer then
+// sends a close frame and closes the connection, ending the read loop too,
+// and an event stream ends its response. The client's orders stop being
+// routed to it but stay listed in client.orders for disconnect.
+func (hub *WsHub) removeLocked(client *hubClient) {
+	if !hub.clients[client] {
+		return
+	}
+
+	delete(hub.clients, client)
+	close(client.send)
+	for orderID := range client.orders {
+		delete(hub.sessions, orderID)
+	}
 }
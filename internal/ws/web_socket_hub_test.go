@@ -0,0 +1,267 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/api"
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func newTestHub(t *testing.T, query string) (*WsHub, *websocket.Conn) {
+	t.Helper()
+
+	hub := NewWsHub()
+	return hub, dialHub(t, hub, query)
+}
+
+func dialHub(t *testing.T, hub *WsHub, query string) *websocket.Conn {
+	t.Helper()
+
+	conn, _ := dialHubWith(t, hub, websocket.DefaultDialer, query)
+	return conn
+}
+
+func dialHubWith(t *testing.T, hub *WsHub, dialer *websocket.Dialer, query string) (*websocket.Conn, *http.Response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/event", hub.HandleWs)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	conn, response, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/event"+query, nil)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, response
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read err: %v", err)
+	}
+
+	return msg
+}
+
+func TestHub_RoutesOnlyToSubscribers(t *testing.T) {
+	hub, conn := newTestHub(t, "")
+	hub.SetSnapshot(ChannelBBO, func(symbol string, _ engine.DepthOptions) []*Message {
+		return []*Message{NewMessage(ChannelBBO, engine.BBO{Symbol: symbol, BidPrice: 1})}
+	})
+
+	conn.WriteJSON(wsRequest{ID: "1", Op: subscribeOp, Channels: []string{ChannelBBO}, Symbols: []string{"btc"}})
+	if msg := readMessage(t, conn); msg.Type != "subscribed" || msg.ID != "1" {
+		t.Fatalf("expected subscribed ack, got %+v", msg)
+	}
+	if msg := readMessage(t, conn); msg.Type != ChannelBBO || msg.Payload.(map[string]any)["symbol"] != "BTC" {
+		t.Fatalf("expected BTC bbo snapshot, got %+v", msg)
+	}
+
+	hub.Publish(ChannelBBO, "ETH", NewMessage(ChannelBBO, engine.BBO{Symbol: "ETH"}))
+	hub.Publish(ChannelTicker, "BTC", NewMessage(ChannelTicker, "ticker"))
+	hub.Publish(ChannelBBO, "BTC", NewMessage(ChannelBBO, engine.BBO{Symbol: "BTC", BidPrice: 2}))
+	if msg := readMessage(t, conn); msg.Type != ChannelBBO || msg.Payload.(map[string]any)["bid_price"] != 2.0 {
+		t.Fatalf("expected only the BTC bbo update, got %+v", msg)
+	}
+
+	conn.WriteJSON(wsRequest{Op: unsubscribeOp, Channels: []string{ChannelBBO}, Symbols: []string{"BTC"}})
+	if msg := readMessage(t, conn); msg.Type != "unsubscribed" {
+		t.Fatalf("expected unsubscribed ack, got %+v", msg)
+	}
+	hub.Publish(ChannelBBO, "BTC", NewMessage(ChannelBBO, engine.BBO{Symbol: "BTC"}))
+
+	conn.WriteJSON(wsRequest{ID: "2", Op: subscribeOp, Channels: []string{"news"}, Symbols: []string{"BTC"}})
+	if msg := readMessage(t, conn); msg.Type != "error" || msg.ID != "2" {
+		t.Fatalf("expected error for unknown channel, got %+v", msg)
+	}
+}
+
+func TestHub_DepthDeltasFollowSubscribedSymbols(t *testing.T) {
+	hub, conn := newTestHub(t, "")
+	hub.SetSnapshot(ChannelDepth, func(symbol string, options engine.DepthOptions) []*Message {
+		return []*Message{NewMessage("depth_snapshot", engine.BookDepth{Symbol: symbol, Seq: 7})}
+	})
+
+	conn.WriteJSON(wsRequest{Op: subscribeOp, Channels: []string{ChannelDepth}, Symbols: []string{"ETH"}})
+	readMessage(t, conn)
+	if msg := readMessage(t, conn); msg.Type != "depth_snapshot" || msg.Payload.(map[string]any)["seq"] != 7.0 {
+		t.Fatalf("expected an ETH depth snapshot at seq 7, got %+v", msg)
+	}
+
+	hub.PublishDepth(func(options engine.DepthOptions) map[string]engine.BookDepth {
+		t.Errorf("streaming clients must not get periodic snapshots")
+		return nil
+	})
+	hub.PublishDepthUpdate("BTC", NewMessage("depth_update", depthUpdate{Symbol: "BTC"}))
+	hub.PublishDepthUpdate("ETH", NewMessage("depth_update", depthUpdate{Symbol: "ETH", Changes: []engine.LevelUpdate{{Seq: 8, Side: engine.Buy, Price: 1, Qty: 2}}}))
+
+	if msg := readMessage(t, conn); msg.Type != "depth_update" || msg.Payload.(map[string]any)["symbol"] != "ETH" {
+		t.Fatalf("expected the ETH depth update only, got %+v", msg)
+	}
+}
+
+func TestHub_GroupedDepthGetsPeriodicSnapshots(t *testing.T) {
+	hub, conn := newTestHub(t, "?aggregation=1")
+
+	conn.WriteJSON(wsRequest{Op: subscribeOp, Channels: []string{ChannelDepth}, Symbols: []string{"ETH"}})
+	readMessage(t, conn)
+
+	hub.PublishDepthUpdate("ETH", NewMessage("depth_update", depthUpdate{Symbol: "ETH"}))
+	hub.PublishDepth(func(options engine.DepthOptions) map[string]engine.BookDepth {
+		if options.Aggregation != 1 {
+			t.Errorf("expected the connection's aggregation, got %+v", options)
+		}
+		return map[string]engine.BookDepth{"BTC": {Symbol: "BTC"}, "ETH": {Symbol: "ETH"}}
+	})
+
+	msg := readMessage(t, conn)
+	var books map[string]engine.BookDepth
+	payload, _ := json.Marshal(msg.Payload)
+	json.Unmarshal(payload, &books)
+	if msg.Type != "snapshot" || len(books) != 1 || books["ETH"].Symbol != "ETH" {
+		t.Fatalf("expected a snapshot of ETH only, got %+v", msg)
+	}
+}
+
+func TestSnapshotWorker_StreamingSnapshotHasEveryLevel(t *testing.T) {
+	e := engine.NewEngine(nil)
+	e.Setup(map[string]*engine.OrderBook{"SYM": engine.NewOrderBook("SYM")})
+	levels := api.MaxDepth + 100
+	for i := 0; i < levels; i++ {
+		e.GetBook("SYM").AddOrder(&engine.Order{ID: "b" + strconv.Itoa(i), Symbol: "SYM", Side: engine.Buy, Price: float64(i + 1), Quantity: 1, Remaining: 1})
+	}
+
+	hub := NewWsHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartWsSnapshotWorker(hub, e, ctx)
+
+	msgs := hub.snapshots[ChannelDepth]("SYM", engine.DepthOptions{Depth: 10})
+	if len(msgs) != 1 {
+		t.Fatalf("expected one snapshot, got %d", len(msgs))
+	}
+	if book := msgs[0].payload.(engine.BookDepth); len(book.Bids) != levels {
+		t.Fatalf("expected all %d bid levels, got %d", levels, len(book.Bids))
+	}
+}
+
+func TestTradeWorker_PushesEachExecution(t *testing.T) {
+	hub, conn := newTestHub(t, "")
+
+	conn.WriteJSON(wsRequest{Op: subscribeOp, Channels: []string{ChannelTrades}, Symbols: []string{"BTC"}})
+	readMessage(t, conn)
+
+	trades := []*engine.Trade{
+		{ID: "t1", Symbol: "BTC", Price: 100, Quantity: 1, TakerSide: engine.Buy},
+		{ID: "t2", Symbol: "BTC", Price: 101, Quantity: 2, TakerSide: engine.Buy},
+	}
+	feed := make(chan []*engine.Trade, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartWsTradeWorker(hub, feed, ctx)
+	feed <- trades
+
+	for _, want := range trades {
+		msg := readMessage(t, conn)
+		trade := msg.Payload.(map[string]any)
+		if msg.Type != "trade" || trade["id"] != want.ID || trade["price"] != want.Price || trade["taker_side"] != "buy" {
+			t.Fatalf("expected trade %s, got %+v", want.ID, msg)
+		}
+	}
+}
+
+func TestHub_EvictsClientWithFullQueue(t *testing.T) {
+	hub := NewWsHub()
+	client := &hubClient{send: make(chan hubMessage, 1), subscriptions: subscriptions{ChannelTrades: {AllSymbols: true}}, orders: map[string]string{}}
+	hub.clients[client] = true
+
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "first"))
+	if len(hub.clients) != 1 {
+		t.Fatalf("expected client to stay while its queue has room")
+	}
+
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "second"))
+	if len(hub.clients) != 0 {
+		t.Fatalf("expected slow client to be removed")
+	}
+	if msg, ok := <-client.send; !ok || string(msg.data) != `{"type":"trade","payload":"first"}` {
+		t.Fatalf("expected queued message before close, got %q", msg.data)
+	}
+	if _, ok := <-client.send; ok {
+		t.Fatalf("expected queue to be closed")
+	}
+
+	hub.Publish(ChannelTrades, "BTC", NewMessage("trade", "third"))
+}
+
+func TestHub_RemovesClosedConnections(t *testing.T) {
+	hub, conn := newTestHub(t, "")
+	conn.WriteJSON(wsRequest{Op: subscribeOp, Channels: []string{ChannelTrades}, Symbols: []string{AllSymbols}})
+	readMessage(t, conn)
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hub.mu.Lock()
+		remaining := len(hub.clients)
+		hub.mu.Unlock()
+		if remaining == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected closed connection to be removed, %d clients left", remaining)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHub_OrdersChannelIsPrivate(t *testing.T) {
+	keys, _ := auth.ParseKeys("alice-key:alice")
+	hub := NewWsHub()
+	hub.UseKeys(keys)
+
+	anonymous := dialHub(t, hub, "")
+	anonymous.WriteJSON(wsRequest{ID: "1", Op: subscribeOp, Channels: []string{ChannelOrders}, Symbols: []string{AllSymbols}})
+	if msg := readMessage(t, anonymous); msg.Type != "error" || msg.Payload.(map[string]any)["code"] != api.CodeUnauthorized {
+		t.Fatalf("expected unauthorized error, got %+v", msg)
+	}
+
+	conn := dialHub(t, hub, "?api_key=alice-key")
+	conn.WriteJSON(wsRequest{Op: subscribeOp, Channels: []string{ChannelOrders}, Symbols: []string{"BTC"}})
+	if msg := readMessage(t, conn); msg.Type != "subscribed" {
+		t.Fatalf("expected subscribed ack, got %+v", msg)
+	}
+
+	hub.PublishOrderUpdate(engine.OrderUpdate{Order: engine.Order{Account: "bob", Symbol: "BTC"}}, NewMessage("order_update", "bob"))
+	hub.PublishOrderUpdate(engine.OrderUpdate{Order: engine.Order{Account: "alice", Symbol: "ETH"}}, NewMessage("order_update", "eth"))
+	hub.PublishOrderUpdate(engine.OrderUpdate{Order: engine.Order{Account: "alice", Symbol: "BTC"}}, NewMessage("order_update", "alice"))
+	if msg := readMessage(t, conn); msg.Type != "order_update" || msg.Payload != "alice" {
+		t.Fatalf("expected only alice's BTC update, got %+v", msg)
+	}
+
+	r := gin.New()
+	r.GET("/event", hub.HandleWs)
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/event?api_key=wrong", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown key, got %d", recorder.Code)
+	}
+}
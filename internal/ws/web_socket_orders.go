@@ -0,0 +1,24 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// StartWsOrderWorker sends each execution report as an order_update message
+// to the connection that placed the order, if it was placed over the hub, and
+// to the connections of the order's account that follow its symbol on the
+// orders channel.
+func StartWsOrderWorker(hub *WsHub, updates <-chan engine.OrderUpdate, ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				hub.PublishOrderUpdate(update, NewMessage("order_update", update))
+			}
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+type tradeMessage struct {
+	ID         string      `json:"id"`
+	Symbol     string      `json:"symbol"`
+	Price      float64     `json:"price"`
+	Quantity   float64     `json:"quantity"`
+	TakerSide  engine.Side `json:"taker_side"`
+	ExecutedAt time.Time   `json:"executed_at"`
+}
+
+// StartWsTradeWorker pushes every execution on the engine's trade feed to the
+// trades subscribers of its symbol, one trade message per execution. The feed
+// only carries trades made since the process started, so subscribers never
+// get old trades as if they had just happened.
+func StartWsTradeWorker(hub *WsHub, trades <-chan []*engine.Trade, ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case batch := <-trades:
+				for _, trade := range batch {
+					hub.Publish(ChannelTrades, trade.Symbol, NewMessage("trade", tradeMessage{
+						ID:         trade.ID,
+						Symbol:     trade.Symbol,
+						Price:      trade.Price,
+						Quantity:   trade.Quantity,
+						TakerSide:  trade.TakerSide,
+						ExecutedAt: trade.ExecutedAt,
+					}))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cemsubasi/orderbook/internal/api"
+	"github.com/cemsubasi/orderbook/internal/engine"
+)
+
+// OrderEntry is the part of the engine that WebSocket trading sessions use.
+type OrderEntry interface {
+	NewOrderID() string
+	Submit(order *engine.Order) error
+	Cancel(symbol string, orderID string) error
+	Amend(symbol string, orderID string, price float64, quantity float64) error
+	FindOrder(orderID string) (engine.Order, bool)
+}
+
+// orderAckPayload confirms that an order request passed validation and was
+// handed to the engine. What the engine then does with it arrives as
+// order_update messages.
+type orderAckPayload struct {
+	Op      string `json:"op"`
+	OrderID string `json:"order_id"`
+	Symbol  string `json:"symbol"`
+}
+
+type orderRejectPayload struct {
+	Op      string `json:"op"`
+	OrderID string `json:"order_id,omitempty"`
+	errorPayload
+}
+
+var errCancelOnDisconnect = &api.Error{Status: http.StatusUnauthorized, Code: api.CodeUnauthorized, Param: "cancel_on_disconnect", Message: "cancel_on_disconnect needs an API key"}
+
+// UseOrderEntry lets authenticated connections place, cancel and amend
+// orders. It must be called before the hub serves connections.
+func (hub *WsHub) UseOrderEntry(orders OrderEntry) {
+	hub.orders = orders
+}
+
+// PublishOrderUpdate queues an execution report for the session that placed
+// the order and for the account's subscribers of its symbol on the orders
+// channel. Private messages are not kept for replay.
+func (hub *WsHub) PublishOrderUpdate(update engine.OrderUpdate, msg *Message) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	order := update.Order
+	session := hub.sessions[order.ID]
+	for client := range hub.clients {
+		if client == session || client.account == order.Account && client.subscriptions.has(ChannelOrders, order.Symbol) {
+			hub.deliver(client, msg, 0)
+		}
+	}
+
+	if session != nil && order.Status != engine.OrderOpen && order.Status != engine.OrderPartiallyFilled {
+		hub.forgetOrder(session, order.ID)
+	}
+}
+
+func (hub *WsHub) handleOrderRequest(client *hubClient, request wsRequest) {
+	if hub.orders == nil {
+		hub.rejectOrder(client, request, &api.Error{Code: api.CodeUnavailable, Message: "order entry is not available"})
+		return
+	}
+	if client.account == "" {
+		hub.rejectOrder(client, request, &api.Error{Code: api.CodeUnauthorized, Message: "order entry needs an API key when connecting"})
+		return
+	}
+
+	switch request.Op {
+	case placeOrderOp:
+		hub.placeOrder(client, request)
+	case cancelOrderOp, amendOrderOp:
+		hub.changeOrder(client, request)
+	}
+}
+
+// placeOrder acknowledges the order before submitting it, so the ack always
+// reaches the client ahead of the order's execution reports. If the engine
+// then refuses the order, a reject with the same order ID follows. Orders
+// from a connection that is already being dropped are not submitted, since
+// nothing would track or cancel them.
+func (hub *WsHub) placeOrder(client *hubClient, request wsRequest) {
+	order, err := request.OrderCreateRequest.Order()
+	if err != nil {
+		hub.rejectOrder(client, request, err)
+		return
+	}
+	order.ID = hub.orders.NewOrderID()
+	order.Account = client.account
+
+	hub.mu.Lock()
+	if !hub.clients[client] {
+		hub.mu.Unlock()
+		return
+	}
+	hub.trackOrder(client, order.ID, order.Symbol)
+	hub.write(client, wsMessage{Type: "order_ack", ID: request.ID, Payload: orderAckPayload{Op: request.Op, OrderID: order.ID, Symbol: order.Symbol}})
+	hub.mu.Unlock()
+
+	if err := hub.orders.Submit(order); err != nil {
+		hub.mu.Lock()
+		hub.forgetOrder(client, order.ID)
+		hub.mu.Unlock()
+
+		request.OrderID = order.ID
+		hub.rejectOrder(client, request, &api.Error{Code: api.CodeInternal, Message: "order could not be accepted"})
+	}
+}
+
+// changeOrder cancels or amends a resting order of the client's account. An
+// order that belongs to another account is reported as not found, the same
+// as one that is no longer resting.
+func (hub *WsHub) changeOrder(client *hubClient, request wsRequest) {
+	if request.OrderID == "" {
+		hub.rejectOrder(client, request, &api.Error{Code: api.CodeInvalidBody, Param: "order_id", Message: "order_id is required"})
+		return
+	}
+
+	amend := api.OrderAmendRequest{Price: request.Price, Quantity: request.Quantity}
+	if request.Op == amendOrderOp {
+		if err := amend.Validate(); err != nil {
+			hub.rejectOrder(client, request, err)
+			return
+		}
+	}
+
+	order, ok := hub.orders.FindOrder(request.OrderID)
+	if !ok || order.Account != client.account {
+		hub.rejectOrder(client, request, &api.Error{Code: api.CodeNotFound, Param: "order_id", Message: "order not found"})
+		return
+	}
+
+	hub.reply(client, wsMessage{Type: "order_ack", ID: request.ID, Payload: orderAckPayload{Op: request.Op, OrderID: order.ID, Symbol: order.Symbol}})
+
+	var err error
+	if request.Op == cancelOrderOp {
+		err = hub.orders.Cancel(order.Symbol, order.ID)
+	} else {
+		err = hub.orders.Amend(order.Symbol, order.ID, amend.Price, amend.Quantity)
+	}
+	if err != nil {
+		hub.rejectOrder(client, request, &api.Error{Code: api.CodeInternal, Message: "request could not be accepted"})
+	}
+}
+
+func (hub *WsHub) rejectOrder(client *hubClient, request wsRequest, err error) {
+	hub.reply(client, wsMessage{Type: "order_reject", ID: request.ID, Payload: orderRejectPayload{Op: request.Op, OrderID: request.OrderID, errorPayload: newErrorPayload(err)}})
+}
+
+// disconnect removes a WebSocket client once its read loop has ended, which
+// is how every lost connection ends: a close, a missed pong, a failed write
+// or eviction. Orders are only placed from the read loop, so none is in
+// flight by then, and a session that asked for it has its resting orders
+// cancelled. The cancellations reach the account's other sessions as usual.
+func (hub *WsHub) disconnect(client *hubClient) {
+	hub.mu.Lock()
+	hub.removeLocked(client)
+	orders := client.orders
+	client.orders = map[string]string{}
+	hub.mu.Unlock()
+
+	if !client.cancelOnDisconnect || hub.orders == nil {
+		return
+	}
+
+	for orderID, symbol := range orders {
+		if order, ok := hub.orders.FindOrder(orderID); !ok || order.Account != client.account {
+			continue
+		}
+		if err := hub.orders.Cancel(symbol, orderID); err != nil {
+			log.Println("cancel on disconnect error:", orderID, err)
+		}
+	}
+}
+
+// trackOrder remembers that client placed orderID, so its execution reports
+// reach the client whatever it subscribed to. The caller must hold hub.mu.
+func (hub *WsHub) trackOrder(client *hubClient, orderID string, symbol string) {
+	if !hub.clients[client] {
+		return
+	}
+
+	hub.sessions[orderID] = client
+	client.orders[orderID] = symbol
+}
+
+// forgetOrder drops an order that is done or was never accepted. The caller
+// must hold hub.mu.
+func (hub *WsHub) forgetOrder(client *hubClient, orderID string) {
+	delete(hub.sessions, orderID)
+	delete(client.orders, orderID)
+}
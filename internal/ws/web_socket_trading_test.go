@@ -0,0 +1,208 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cemsubasi/orderbook/internal/api"
+	"github.com/cemsubasi/orderbook/internal/auth"
+	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type fakeOrderEntry struct {
+	mu        sync.Mutex
+	nextID    int
+	orders    map[string]engine.Order
+	submitted []*engine.Order
+	cancelled []string
+}
+
+func (entry *fakeOrderEntry) NewOrderID() string {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	entry.nextID++
+	return "o" + strconv.Itoa(entry.nextID)
+}
+
+func (entry *fakeOrderEntry) Submit(order *engine.Order) error {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	entry.submitted = append(entry.submitted, order)
+	entry.orders[order.ID] = *order
+	return nil
+}
+
+func (entry *fakeOrderEntry) Cancel(symbol string, orderID string) error {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	entry.cancelled = append(entry.cancelled, orderID)
+	return nil
+}
+
+func (entry *fakeOrderEntry) Amend(symbol string, orderID string, price float64, quantity float64) error {
+	return nil
+}
+
+func (entry *fakeOrderEntry) FindOrder(orderID string) (engine.Order, bool) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	order, ok := entry.orders[orderID]
+	return order, ok
+}
+
+func newTradingHub(t *testing.T) (*WsHub, *fakeOrderEntry) {
+	t.Helper()
+
+	keys, _ := auth.ParseKeys("alice-key:alice")
+	entry := &fakeOrderEntry{orders: map[string]engine.Order{"bob-order": {ID: "bob-order", Symbol: "BTC", Account: "bob"}}}
+	hub := NewWsHub()
+	hub.UseKeys(keys)
+	hub.UseOrderEntry(entry)
+
+	return hub, entry
+}
+
+func expectReject(t *testing.T, conn *websocket.Conn, code string, param string) {
+	t.Helper()
+
+	msg := readMessage(t, conn)
+	payload, _ := msg.Payload.(map[string]any)
+	if msg.Type != "order_reject" || payload["code"] != code || (param != "" && payload["param"] != param) {
+		t.Fatalf("expected %s reject on %q, got %+v", code, param, msg)
+	}
+}
+
+func TestTrading_PlaceAndCancelOrders(t *testing.T) {
+	hub, entry := newTradingHub(t)
+	conn := dialHub(t, hub, "?api_key=alice-key")
+
+	conn.WriteJSON(map[string]any{"op": placeOrderOp, "id": "c1", "symbol": "btc", "side": "BUY", "price": 100, "quantity": 1})
+	msg := readMessage(t, conn)
+	if msg.Type != "order_ack" || msg.ID != "c1" || msg.Payload.(map[string]any)["order_id"] != "o1" || msg.Payload.(map[string]any)["symbol"] != "BTC" {
+		t.Fatalf("expected ack for o1, got %+v", msg)
+	}
+	hub.PublishOrderUpdate(engine.OrderUpdate{Type: engine.OrderUpdateNew, Order: engine.Order{ID: "o1", Symbol: "BTC", Account: "alice", Status: engine.OrderOpen}}, NewMessage("order_update", "o1 new"))
+	if msg := readMessage(t, conn); msg.Type != "order_update" || msg.Payload != "o1 new" {
+		t.Fatalf("expected the placing session to get the report unsubscribed, got %+v", msg)
+	}
+
+	conn.WriteJSON(map[string]any{"op": placeOrderOp, "id": "c2", "symbol": "BTC", "side": "buy", "price": -1, "quantity": 1})
+	expectReject(t, conn, api.CodeInvalidBody, "price")
+
+	// Requests are handled in order, so o1 has been submitted by now.
+	entry.mu.Lock()
+	if order := entry.submitted[0]; order.Account != "alice" || order.Side != engine.Buy {
+		t.Fatalf("expected alice's normalized buy order, got %+v", order)
+	}
+	entry.mu.Unlock()
+
+	conn.WriteJSON(map[string]any{"op": cancelOrderOp, "id": "c3", "order_id": "bob-order"})
+	expectReject(t, conn, api.CodeNotFound, "order_id")
+
+	conn.WriteJSON(map[string]any{"op": amendOrderOp, "id": "c4", "order_id": "o1", "price": 100, "quantity": 0})
+	expectReject(t, conn, api.CodeInvalidBody, "quantity")
+
+	conn.WriteJSON(map[string]any{"op": cancelOrderOp, "id": "c5", "order_id": "o1"})
+	if msg := readMessage(t, conn); msg.Type != "order_ack" || msg.ID != "c5" {
+		t.Fatalf("expected cancel ack, got %+v", msg)
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if len(entry.cancelled) != 1 || entry.cancelled[0] != "o1" {
+		t.Fatalf("expected o1 to be cancelled, got %v", entry.cancelled)
+	}
+}
+
+func TestTrading_NeedsAnAPIKey(t *testing.T) {
+	hub, entry := newTradingHub(t)
+	conn := dialHub(t, hub, "")
+
+	conn.WriteJSON(map[string]any{"op": placeOrderOp, "id": "c1", "symbol": "BTC", "side": "buy", "price": 100, "quantity": 1})
+	expectReject(t, conn, api.CodeUnauthorized, "")
+	if len(entry.submitted) != 0 {
+		t.Fatalf("expected no order to be submitted")
+	}
+}
+
+func TestTrading_CancelOnDisconnect(t *testing.T) {
+	hub, entry := newTradingHub(t)
+	conn := dialHub(t, hub, "?api_key=alice-key&cancel_on_disconnect=true")
+
+	for _, id := range []string{"c1", "c2"} {
+		conn.WriteJSON(map[string]any{"op": placeOrderOp, "id": id, "symbol": "BTC", "side": "buy", "price": 100, "quantity": 1})
+		if msg := readMessage(t, conn); msg.Type != "order_ack" {
+			t.Fatalf("expected ack, got %+v", msg)
+		}
+	}
+	// Wait for both submits, then let o2 fill so it no longer rests.
+	conn.WriteJSON(map[string]any{"op": cancelOrderOp, "id": "c3", "order_id": "bob-order"})
+	expectReject(t, conn, api.CodeNotFound, "order_id")
+	entry.mu.Lock()
+	delete(entry.orders, "o2")
+	entry.mu.Unlock()
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entry.mu.Lock()
+		cancelled := append([]string(nil), entry.cancelled...)
+		entry.mu.Unlock()
+		if len(cancelled) > 0 {
+			if len(cancelled) != 1 || cancelled[0] != "o1" {
+				t.Fatalf("expected only the resting o1 to be cancelled, got %v", cancelled)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected o1 to be cancelled after the connection closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r := gin.New()
+	r.GET("/event", hub.HandleWs)
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/event?cancel_on_disconnect=true", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for cancel_on_disconnect without a key, got %d", recorder.Code)
+	}
+}
+
+func TestTrading_KeepsOrdersOnDisconnectByDefault(t *testing.T) {
+	hub, entry := newTradingHub(t)
+	conn := dialHub(t, hub, "?api_key=alice-key")
+
+	conn.WriteJSON(map[string]any{"op": placeOrderOp, "id": "c1", "symbol": "BTC", "side": "buy", "price": 100, "quantity": 1})
+	readMessage(t, conn)
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hub.mu.Lock()
+		remaining := len(hub.clients)
+		hub.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected closed connection to be removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if len(entry.cancelled) != 0 {
+		t.Fatalf("expected orders to stay without cancel_on_disconnect, got %v", entry.cancelled)
+	}
+}
@@ -2,14 +2,56 @@ package ws
 
 import (
 	"context"
-	"encoding/json"
-	"log"
 	"time"
 
 	"github.com/cemsubasi/orderbook/internal/engine"
+	"github.com/cemsubasi/orderbook/internal/ticker"
 )
 
-func StartWsSnapshotWorker(hub *WsHub, engine *engine.Engine, ctx context.Context) {
+// StartWsSnapshotWorker answers depth subscriptions with a depth_snapshot of
+// each book. Connections that stream deltas get every level, since the deltas
+// that follow cover every level too and a capped snapshot would leave the
+// client unable to apply updates beyond the cap. The
+// rest get a fresh snapshot of their grouped or cumulative levels every two
+// seconds.
+func StartWsSnapshotWorker(hub *WsHub, matchEngine *engine.Engine, ctx context.Context) {
+	hub.SetSnapshot(ChannelDepth, func(symbol string, options engine.DepthOptions) []*Message {
+		if streamsDepth(options) {
+			options.Depth = fullDepth.Depth
+		}
+
+		var books []engine.BookDepth
+		if symbol == AllSymbols {
+			for _, book := range matchEngine.Depths(options) {
+				books = append(books, book)
+			}
+		} else if book, ok := matchEngine.Depth(symbol, options); ok {
+			books = append(books, book)
+		}
+
+		var msgs []*Message
+		for _, book := range books {
+			msgs = append(msgs, NewMessage("depth_snapshot", book))
+		}
+		return msgs
+	})
+
+	go func() {
+		ticker := time.NewTicker(time.Second * 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				hub.PublishDepth(matchEngine.Depths)
+			}
+		}
+	}()
+}
+
+func StartWsTickerWorker(hub *WsHub, tracker *ticker.Tracker, ctx context.Context) {
 	go func() {
 		ticker := time.NewTicker(time.Second * 2)
 		defer ticker.Stop()
@@ -19,26 +61,109 @@ func StartWsSnapshotWorker(hub *WsHub, engine *engine.Engine, ctx context.Contex
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				snapshot := map[string]map[string]any{}
-				books := engine.GetBooks()
-				for _, book := range books {
-					bids, asks := book.Snapshot(10)
-					snapshot[book.Symbol] = map[string]any{
-						"bids": bids,
-						"asks": asks,
+				for _, t := range tracker.Tickers() {
+					hub.Publish(ChannelTicker, t.Symbol, NewMessage(ChannelTicker, t))
+				}
+			}
+		}
+	}()
+}
+
+// StartWsBBOWorker checks the top of every book frequently but only sends the
+// ones that moved since the last check, so quiet books cost nothing on the wire.
+// New subscribers get the current top of book straight away.
+func StartWsBBOWorker(hub *WsHub, matchEngine *engine.Engine, ctx context.Context) {
+	hub.SetSnapshot(ChannelBBO, func(symbol string, _ engine.DepthOptions) []*Message {
+		var bbos []engine.BBO
+		if symbol == AllSymbols {
+			bbos = matchEngine.BBOs()
+		} else if bbo, ok := matchEngine.BBO(symbol); ok {
+			bbos = append(bbos, bbo)
+		}
+
+		var msgs []*Message
+		for _, bbo := range bbos {
+			msgs = append(msgs, NewMessage(ChannelBBO, bbo))
+		}
+		return msgs
+	})
+
+	go func() {
+		ticker := time.NewTicker(time.Millisecond * 100)
+		defer ticker.Stop()
+
+		sent := map[string]engine.BBO{}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, bbo := range matchEngine.BBOs() {
+					if last, ok := sent[bbo.Symbol]; ok && last == bbo {
+						continue
 					}
+					sent[bbo.Symbol] = bbo
+
+					hub.Publish(ChannelBBO, bbo.Symbol, NewMessage(ChannelBBO, bbo))
 				}
+			}
+		}
+	}()
+}
 
-				payload, err := json.Marshal(map[string]any{
-					"type":    "snapshot",
-					"payload": snapshot,
-				})
-				if err != nil {
-					log.Println("snapshot marshal error:", err)
-					continue
+type depthUpdate struct {
+	Symbol  string               `json:"symbol"`
+	Changes []engine.LevelUpdate `json:"changes"`
+}
+
+const bookUpdateBatch = 256
+
+// StartWsBookWorker forwards every per-order book change as an l3 message and
+// its level change to depth subscribers. Depth changes that are already
+// waiting are sent together, one depth_update per symbol, each change still
+// carrying its own seq. The worker keeps its own copy of the levels to
+// checksum each change; it must be started before any order reaches the
+// engine, or the first changes it gets are already in its copy and are not
+// sent.
+func StartWsBookWorker(hub *WsHub, matchEngine *engine.Engine, updates <-chan engine.BookUpdate, maskIDs bool, ctx context.Context) {
+	books := newLevelBooks(matchEngine)
+	go func() {
+		batch := make([]engine.BookUpdate, 0, bookUpdateBatch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				batch = append(batch[:0], update)
+			drain:
+				for len(batch) < bookUpdateBatch {
+					select {
+					case update := <-updates:
+						batch = append(batch, update)
+					default:
+						break drain
+					}
 				}
 
-				hub.Broadcast(payload)
+				var symbols []string
+				changes := map[string][]engine.LevelUpdate{}
+				for _, update := range batch {
+					if change, ok := books.change(update); ok {
+						if _, ok := changes[update.Symbol]; !ok {
+							symbols = append(symbols, update.Symbol)
+						}
+						changes[update.Symbol] = append(changes[update.Symbol], change)
+					}
+
+					if maskIDs {
+						update = update.Mask()
+					}
+					hub.Publish(ChannelL3, update.Symbol, NewMessage(ChannelL3, update))
+				}
+
+				for _, symbol := range symbols {
+					hub.PublishDepthUpdate(symbol, NewMessage("depth_update", depthUpdate{Symbol: symbol, Changes: changes[symbol]}))
+				}
 			}
 		}
 	}()